@@ -1,9 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 
@@ -14,7 +15,6 @@ import (
 
 var (
 	operation       string
-	stashNumber     int
 	validationError error
 )
 
@@ -23,34 +23,91 @@ func main() {
 }
 
 func Init() int {
-	operation, stashNumber, validationError = validation.ArgValidation(os.Args[1:])
+	args, remote, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Argument error: %v\n", err)
+		return 1
+	}
+	operation, validationError = validation.ArgValidation(args)
 	if validationError != nil {
 		fmt.Fprintf(os.Stderr, "Argument error: %v\n", validationError)
 		return 1
 	}
 
-	config.LoadConfig(config.ConfigName)
+	// flags that override config keys
+	configFlags := map[string]string{"remote": remote}
+	loadErr := config.Load(configFlags)
+	if loadErr != nil && operation != "help" && operation != "config" {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", loadErr)
+		return 1
+	}
+
+	// arguments of the command itself, without the operation
+	var cmdArgs []string
+	if len(args) > 1 {
+		cmdArgs = args[1:]
+	}
 
 	switch operation {
 	case "help":
 		return help()
 	case "push":
 		pushCmd := flag.NewFlagSet("push", flag.ExitOnError)
-		var commitMessage string
-		pushCmd.StringVarP(&commitMessage, "message", "m", "", "Add a descriptive message to a stash")
+		var opts service.PushOptions
+		pushCmd.StringVarP(&opts.Message, "message", "m", "", "Add a descriptive message to a stash")
+		pushCmd.BoolVar(&opts.StagedOnly, "staged", false, "Stash only the changes that are staged in the index")
+		pushCmd.BoolVarP(&opts.KeepIndex, "keep-index", "k", false, "Stash everything but keep the staged changes locally")
+		pushCmd.BoolVar(&opts.Keep, "keep", false, "Publish the stash but keep the working tree and index unchanged")
+		pushCmd.BoolVar(&opts.WithCommits, "with-commits", false, "Include local commits that are not on the upstream branch")
+		var retry bool
+		pushCmd.BoolVar(&retry, "retry", false, "Publish stashes whose push failed earlier")
 
-		args := []string{}
-		if len(os.Args) > 2 {
-			args = os.Args[2:]
+		pushCmd.Parse(cmdArgs)
+		// everything after the flags (usually separated by --) is treated as pathspec
+		opts.Pathspecs = pushCmd.Args()
+		if retry {
+			return pushRetry()
 		}
-		pushCmd.Parse(args)
-		return push(commitMessage)
+		return push(opts)
 	case "pop":
-		return pop()
+		var opts service.ApplyOptions
+		sel, ok := parseSelector("pop", cmdArgs, applyFlags(&opts))
+		if !ok {
+			return 1
+		}
+		return pop(sel, opts)
+	case "apply":
+		var opts service.ApplyOptions
+		sel, ok := parseSelector("apply", cmdArgs, applyFlags(&opts))
+		if !ok {
+			return 1
+		}
+		return apply(sel, opts)
 	case "list":
-		return list()
+		listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+		var opts service.ListOptions
+		listCmd.StringVarP(&opts.Format, "format", "f", "table", "Output format: table, json or porcelain")
+		listCmd.BoolVar(&opts.Offline, "offline", false, "List from the cached remote-tracking refs without fetching")
+		listCmd.StringVar(&opts.Scope.Author, "author", "", "List only stashes whose author name or email contains the given text")
+		scopeFlags(&opts.Scope)(listCmd)
+		listCmd.Parse(cmdArgs)
+		return list(opts)
 	case "drop":
-		return drop()
+		sel, ok := parseSelector("drop", cmdArgs, nil)
+		if !ok {
+			return 1
+		}
+		return drop(sel)
+	case "show":
+		var opts service.ShowOptions
+		sel, ok := parseSelector("show", cmdArgs, func(showCmd *flag.FlagSet) {
+			showCmd.BoolVar(&opts.Stat, "stat", false, "Show a diffstat summary instead of the patch")
+			showCmd.BoolVar(&opts.NameOnly, "name-only", false, "Show only the names of changed files")
+		})
+		if !ok {
+			return 1
+		}
+		return show(sel, opts)
 	case "cleanup":
 		// using flagset here because i want to have specific flags for cleanup only
 		cleanupCmd := flag.NewFlagSet("cleanup", flag.ExitOnError)
@@ -58,9 +115,16 @@ func Init() int {
 		var confirmation bool
 		cleanupCmd.IntVarP(&days, "days", "d", config.CleanUpTimeInDays, "Override the cleanup retention period in days")
 		cleanupCmd.BoolVarP(&confirmation, "yes", "y", config.SkipConfirmations, "Decide whether or not to skip the manual confirmation of stash deletion")
-		cleanupCmd.Parse(os.Args[2:])
+		var scope service.Scope
+		cleanupCmd.StringVar(&scope.Author, "author", "", "Clean up only stashes whose author name or email contains the given text")
+		scopeFlags(&scope)(cleanupCmd)
+		cleanupCmd.Parse(cmdArgs)
 		config.UpdateSkipConfirmations(confirmation)
-		return cleanup(days)
+		return cleanup(days, scope)
+	case "migrate":
+		return migrate()
+	case "config":
+		return configCommand(cmdArgs, configFlags, loadErr)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown operation: %v\n", operation)
 		os.Exit(1)
@@ -68,9 +132,37 @@ func Init() int {
 	return 0
 }
 
-func list() int {
-	if err := service.HandleList(); err != nil {
-		fmt.Println("Error fetching 8stashes")
+// parseGlobalFlags removes the flags shared by all commands from args. They may
+// appear before or after the operation but not after a "--" separator.
+func parseGlobalFlags(args []string) ([]string, string, error) {
+	var rest []string
+	var remote string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(rest, args[i:]...), remote, nil
+		case arg == "--remote":
+			if i+1 >= len(args) || args[i+1] == "" {
+				return nil, "", errors.New("--remote requires a remote name")
+			}
+			i++
+			remote = args[i]
+		case strings.HasPrefix(arg, "--remote="):
+			remote = strings.TrimPrefix(arg, "--remote=")
+			if remote == "" {
+				return nil, "", errors.New("--remote requires a remote name")
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, remote, nil
+}
+
+func list(opts service.ListOptions) int {
+	if err := service.HandleList(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching 8stashes: %v\n", err)
 		return 1
 	}
 	return 0
@@ -81,8 +173,8 @@ func help() int {
 	return 0
 }
 
-func push(commitMessage string) int {
-	stashName, err := service.HandlePush(commitMessage)
+func push(opts service.PushOptions) int {
+	stashName, err := service.HandlePush(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error during push operation: %v\n", err)
 		return 1
@@ -91,24 +183,147 @@ func push(commitMessage string) int {
 	return 0
 }
 
-func pop() int {
-	if err := service.HandlePop(strconv.Itoa(stashNumber)); err != nil {
+// parseSelector parses the stash selector shared by pop, apply, drop and show.
+// extraFlags registers command specific flags on the same FlagSet.
+func parseSelector(name string, args []string, extraFlags func(*flag.FlagSet)) (service.StashSelector, bool) {
+	var sel service.StashSelector
+	cmd := flag.NewFlagSet(name, flag.ExitOnError)
+	cmd.StringVar(&sel.Author, "author", "", "Select stashes whose author name or email contains the given text")
+	cmd.StringVar(&sel.Grep, "grep", "", "Select stashes whose message matches the given regular expression")
+	if extraFlags != nil {
+		extraFlags(cmd)
+	}
+	cmd.Parse(args)
+	if cmd.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Argument error: %s takes at most one stash id or selector\n", name)
+		return sel, false
+	}
+	sel.Query = cmd.Arg(0)
+	return sel, true
+}
+
+func applyFlags(opts *service.ApplyOptions) func(*flag.FlagSet) {
+	return func(cmd *flag.FlagSet) {
+		cmd.BoolVar(&opts.Squash, "squash", false, "Apply local commits carried by the stash as unstaged changes instead of replaying them")
+		scopeFlags(&opts.Scope)(cmd)
+	}
+}
+
+// scopeFlags registers --mine and --all, which narrow or widen the default scope from the config.
+func scopeFlags(scope *service.Scope) func(*flag.FlagSet) {
+	return func(cmd *flag.FlagSet) {
+		cmd.BoolVar(&scope.Mine, "mine", false, "Only consider stashes authored by your git user")
+		cmd.BoolVar(&scope.All, "all", false, "Consider the stashes of everyone, ignoring the scope setting")
+	}
+}
+
+func pushRetry() int {
+	published, err := service.HandlePushRetry()
+	for _, branch := range published {
+		fmt.Printf("Published pending stash: %s\n", branch)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error during push operation: %v\n", err)
+		return 1
+	}
+	if len(published) == 0 {
+		fmt.Println("No pending stashes to publish.")
+	}
+	return 0
+}
+
+func pop(sel service.StashSelector, opts service.ApplyOptions) int {
+	if err := service.HandlePop(sel, opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error during pop operation: %v\n", err)
 		return 1
 	}
 	return 0
 }
 
-func drop() int {
-	if err := service.HandleDrop(strconv.Itoa(stashNumber)); err != nil {
+func apply(sel service.StashSelector, opts service.ApplyOptions) int {
+	if err := service.HandleApply(sel, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during apply operation: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func drop(sel service.StashSelector) int {
+	if err := service.HandleDrop(sel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during drop operation: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func show(sel service.StashSelector, opts service.ShowOptions) int {
+	if err := service.HandleShow(sel, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during show operation: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func migrate() int {
+	if err := service.HandleMigrate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error during migrate operation: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// configCommand runs config and its subcommands. Only showing values needs a config
+// that loads; validate, set and init are how a broken one gets fixed.
+func configCommand(args []string, configFlags map[string]string, loadErr error) int {
+	configCmd := flag.NewFlagSet("config", flag.ExitOnError)
+	var showOrigin, user, force bool
+	configCmd.BoolVar(&showOrigin, "show-origin", false, "Show the file, git config key, variable or flag each value comes from")
+	configCmd.BoolVar(&user, "user", false, "Write the user config in ~/.config/8stash instead of the repository's .8stash.yaml")
+	configCmd.BoolVar(&force, "force", false, "Let init overwrite an existing config file")
+	configCmd.Parse(args)
+
+	sub, subArgs := configCmd.Arg(0), configCmd.Args()
+	if len(subArgs) > 0 {
+		subArgs = subArgs[1:]
+	}
+	wantArgs := map[string]int{"": 0, "get": 1, "set": 2, "init": 0, "validate": 0}
+	n, ok := wantArgs[sub]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Argument error: unknown config command %q; use get, set, init or validate\n", sub)
+		return 1
+	}
+	if len(subArgs) != n {
+		fmt.Fprintf(os.Stderr, "Argument error: config %s takes %d argument(s)\n", sub, n)
+		return 1
+	}
+	if loadErr != nil && (sub == "" || sub == "get") {
+		fmt.Fprintf(os.Stderr, "Config error: %v\n", loadErr)
+		return 1
+	}
+
+	var err error
+	switch sub {
+	case "":
+		service.HandleConfigShow(showOrigin)
+	case "get":
+		err = service.HandleConfigGet(subArgs[0])
+	case "set":
+		err = service.HandleConfigSet(subArgs[0], subArgs[1], user)
+	case "init":
+		err = service.HandleConfigInit(user, force)
+	case "validate":
+		err = service.HandleConfigValidate(configFlags)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error during config operation: %v\n", err)
 		return 1
 	}
 	return 0
 }
 
-func cleanup(days int) int {
+func cleanup(days int, scope service.Scope) int {
 	config.UpdateCleanupRetentionTime(days)
-	if err := service.HandleCleanup(); err != nil {
+	if err := service.HandleCleanup(scope); err != nil {
 		fmt.Fprintf(os.Stderr, "Error during cleanup operation: %v\n", err)
 		return 1
 	}
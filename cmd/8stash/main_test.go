@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -165,6 +166,47 @@ func TestInit_DropCommand_RemovesBranch(t *testing.T) {
 	assert.False(t, refExists(refs, "refs/heads/"+fullBranch), "branch should be deleted after drop")
 }
 
+func TestInit_DropCommand_AmbiguousOrUnknown_PrintsErrorAndFails(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"11", "a.txt", "a", time.Now().Add(-time.Hour))
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"12", "b.txt", "b", time.Now())
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}))
+	test.FetchAll(t, repo)
+	restoreArgs := stubArgs(t, "8stash", "drop", "--grep", ".")
+
+	// Act
+	_, stderr, exitCode := runInit(t)
+	restoreArgs()
+
+	// Assert
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr, "Error during drop operation:")
+	assert.Contains(t, stderr, config.BranchPrefix+"11")
+	assert.Contains(t, stderr, config.BranchPrefix+"12")
+
+	// Act
+	defer stubArgs(t, "8stash", "drop", "99")()
+	_, stderr, exitCode = runInit(t)
+
+	// Assert
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr, "Error during drop operation: no stash matches 99")
+	refs := listRemoteRefs(t, repo)
+	assert.True(t, refExists(refs, "refs/heads/"+config.BranchPrefix+"11"))
+	assert.True(t, refExists(refs, "refs/heads/"+config.BranchPrefix+"12"))
+}
+
 func TestInit_CleanupCommand_RemovesOldStashes(t *testing.T) {
 	// Arrange
 	restoreConfig := snapshotConfig(t)
@@ -257,10 +299,43 @@ func TestInit_PushCommand_WithMessage_StoresMessage(t *testing.T) {
 	assert.Equal(t, customMessage, commit.Message)
 }
 
+func TestInit_PushCommand_WithPathspec_KeepsOtherChanges(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "stash-me.txt"), []byte("stash"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "keep-me.txt"), []byte("keep"), 0o644))
+
+	defer stubArgs(t, "8stash", "push", "--", "stash-me.txt")()
+
+	// Act
+	stdout, stderr, exitCode := runInit(t)
+
+	// Assert
+	require.Equal(t, 0, exitCode)
+	assert.Empty(t, strings.TrimSpace(stderr))
+
+	stashBranch := parseStashBranch(t, stdout)
+	refs := listRemoteRefs(t, repo)
+	assert.True(t, refExists(refs, "refs/heads/"+stashBranch), "expected remote branch %s", stashBranch)
+
+	_, err = os.Stat(filepath.Join(localPath, "stash-me.txt"))
+	assert.True(t, os.IsNotExist(err))
+	data, err := os.ReadFile(filepath.Join(localPath, "keep-me.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "keep", string(data))
+}
+
 func runInit(t *testing.T) (string, string, int) {
 	t.Helper()
 	operation = ""
-	stashNumber = 0
 	validationError = nil
 	return captureOutputs(t, func() int { return Init() })
 }
@@ -296,6 +371,143 @@ func captureOutputs(t *testing.T, fn func() int) (string, string, int) {
 	return stdoutBuf.String(), stderrBuf.String(), exitCode
 }
 
+func TestInit_RemoteFlag_PushesAndListsOnThatRemote(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	teamPath := t.TempDir()
+	_, err = git.PlainInit(teamPath, true)
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{Name: "team", URLs: []string{teamPath}})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("work in progress"), 0o644))
+	restoreArgs := stubArgs(t, "8stash", "push", "--remote", "team", "-m", "for the team")
+
+	// Act
+	stdout, stderr, exitCode := runInit(t)
+	restoreArgs()
+
+	// Assert
+	require.Equal(t, 0, exitCode, stderr)
+	stashBranch := parseStashBranch(t, stdout)
+	team, err := git.PlainOpen(teamPath)
+	require.NoError(t, err)
+	_, err = team.Reference(plumbing.NewBranchReferenceName(stashBranch), false)
+	assert.NoError(t, err, "stash should be pushed to the team remote")
+	assert.False(t, refExists(listRemoteRefs(t, repo), "refs/heads/"+stashBranch), "stash should not be on origin")
+
+	// Act
+	defer stubArgs(t, "8stash", "--remote=team", "list")()
+	stdout, stderr, exitCode = runInit(t)
+
+	// Assert
+	require.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, stashBranch)
+}
+
+func TestInit_ConfigShowOrigin_PrintsValuesWithTheirLayer(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, config.ConfigName), []byte("retention_days: 7\n"), 0o644))
+	t.Setenv("EIGHTSTASH_SCOPE", "mine")
+	defer stubArgs(t, "8stash", "--remote", "team", "config", "--show-origin")()
+
+	// Act
+	stdout, stderr, exitCode := runInit(t)
+
+	// Assert
+	require.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "file:"+filepath.Join(localPath, config.ConfigName)+"\tretention_days=7\n")
+	assert.Contains(t, stdout, "env:EIGHTSTASH_SCOPE\tscope=mine\n")
+	assert.Contains(t, stdout, "flag:--remote\tremote=team\n")
+	assert.Contains(t, stdout, "default\tstorage=branches\n")
+}
+
+func TestInit_InvalidConfig_FailsAndValidateListsProblems(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+	configPath := filepath.Join(localPath, config.ConfigName)
+	require.NoError(t, os.WriteFile(configPath, []byte("retention_days: 7\nscope: team\nnaming:\n  hash_type: dice\n"), 0o644))
+	restoreArgs := stubArgs(t, "8stash", "list")
+
+	// Act
+	_, stderr, exitCode := runInit(t)
+	restoreArgs()
+
+	// Assert
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr, "Config error: invalid config, 2 problems:")
+
+	// Act
+	defer stubArgs(t, "8stash", "config", "validate")()
+	stdout, stderr, exitCode := runInit(t)
+
+	// Assert
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stdout, configPath+":2: scope must be either all or mine\n")
+	assert.Contains(t, stdout, configPath+":4: naming.hash_type must be numeric, uuid, words or sequential")
+	assert.Contains(t, stderr, "found 2 config problem(s)")
+}
+
+func TestInit_ConfigSetThenGet_RoundTrips(t *testing.T) {
+	// Arrange
+	restoreConfig := snapshotConfig(t)
+	defer restoreConfig()
+
+	localPath, cleanupRepo := test.SetupTestRepo(t)
+	defer cleanupRepo()
+	restoreArgs := stubArgs(t, "8stash", "config", "init")
+	_, stderr, exitCode := runInit(t)
+	restoreArgs()
+	require.Equal(t, 0, exitCode, stderr)
+
+	// Act
+	restoreArgs = stubArgs(t, "8stash", "config", "set", "naming.hash_type", "words")
+	_, stderr, exitCode = runInit(t)
+	restoreArgs()
+	require.Equal(t, 0, exitCode, stderr)
+
+	defer stubArgs(t, "8stash", "config", "get", "naming.hash_type")()
+	stdout, stderr, exitCode := runInit(t)
+
+	// Assert
+	require.Equal(t, 0, exitCode, stderr)
+	assert.Equal(t, "words\n", stdout)
+	data, err := os.ReadFile(filepath.Join(localPath, config.ConfigName))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), config.DefaultFile()), "the commented defaults should be kept")
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	// Arrange
+	args := []string{"--remote", "team", "push", "-m", "msg", "--", "--remote=path"}
+
+	// Act
+	rest, remote, err := parseGlobalFlags(args)
+	_, _, missingErr := parseGlobalFlags([]string{"list", "--remote"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "team", remote)
+	assert.Equal(t, []string{"push", "-m", "msg", "--", "--remote=path"}, rest)
+	assert.ErrorContains(t, missingErr, "--remote requires a remote name")
+}
+
 func stubArgs(t *testing.T, args ...string) func() {
 	t.Helper()
 	orig := os.Args
@@ -309,11 +521,16 @@ func stubArgs(t *testing.T, args ...string) func() {
 
 func snapshotConfig(t *testing.T) func() {
 	t.Helper()
+	// keep the user config of whoever runs the tests out of them
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	origPrefix := config.BranchPrefix
 	origRetention := config.CleanUpTimeInDays
 	origSkip := config.SkipConfirmations
 	origHashType := config.NamingHashType
 	origHashRange := config.HashRange
+	origRemote := config.Remote
+	origTemplate := config.NamingTemplate
+	origScope := config.DefaultScope
 
 	return func() {
 		config.BranchPrefix = origPrefix
@@ -321,6 +538,9 @@ func snapshotConfig(t *testing.T) func() {
 		config.SkipConfirmations = origSkip
 		config.NamingHashType = origHashType
 		config.HashRange = origHashRange
+		config.Remote = origRemote
+		config.NamingTemplate = origTemplate
+		config.DefaultScope = origScope
 	}
 }
 
@@ -10,16 +10,43 @@ const MaxNumericrange = math.MaxInt32
 const MinNumericRange = 1
 
 var BranchPrefix = "8stash/"
+
+// NamingTemplate lays out stash branch names. Empty means BranchPrefix followed by the id.
+var NamingTemplate = ""
 var CleanUpTimeInDays = 30
 var NamingHashType = HashNumeric
 var HashRange = 9999
 var SkipConfirmations = false
+var Storage = StorageBranches
+
+// DefaultScope applies to list, pop and cleanup unless --all or --author is given.
+var DefaultScope = ScopeAll
+
+// Remote holds the stashes. Empty means the tracking remote of the current branch, or origin.
+var Remote = ""
+var AuthUsername = ""
+// TokenEnvVar is the default of auth.token_env, the variable holding an HTTPS access token.
+const TokenEnvVar = "EIGHTSTASH_TOKEN"
+
+// SSHKeyEnvVar is a shorter variable for auth.ssh_key, read when EIGHTSTASH_AUTH_SSH_KEY is not set.
+const SSHKeyEnvVar = "EIGHTSTASH_SSH_KEY"
+
+var AuthTokenEnv = TokenEnvVar
+var UseCredentialHelper = true
+var SSHKeyFile = ""
+var SSHUser = ""
+var KnownHostsFile = ""
 
 func UpdateApplicationConfiguration(cfg *YamlConfig) {
 	updateBranchPrefix(cfg.CustomBranchPrefix)
+	updateNamingTemplate(cfg.Naming.Template)
 	UpdateCleanupRetentionTime(cfg.RetentionDays)
 	updateNamingHashType(cfg.Naming.HashType)
 	updateHashRange(cfg.Naming.Range, cfg.Naming.HashType)
+	UpdateRemote(cfg.Remote)
+	updateStorage(cfg.Storage)
+	updateScope(cfg.Scope)
+	updateAuth(cfg.Auth)
 }
 
 func updateHashRange(i int, ht HashType) {
@@ -34,6 +61,15 @@ func updateBranchPrefix(s string) {
 	}
 }
 
+// updateNamingTemplate also narrows BranchPrefix to the fixed start of the template,
+// which is where stashes are fetched and listed from.
+func updateNamingTemplate(t string) {
+	if t != "" {
+		NamingTemplate = t
+		BranchPrefix = TemplatePrefix(t)
+	}
+}
+
 func UpdateCleanupRetentionTime(i int) {
 	if i > 0 {
 		CleanUpTimeInDays = i
@@ -46,6 +82,45 @@ func updateNamingHashType(h HashType) {
 	}
 }
 
+func updateStorage(s StorageMode) {
+	if s != "" {
+		Storage = s
+	}
+}
+
+func updateScope(s ScopeMode) {
+	if s != "" {
+		DefaultScope = s
+	}
+}
+
+func updateAuth(a AuthConfig) {
+	if a.Username != "" {
+		AuthUsername = a.Username
+	}
+	if a.TokenEnv != "" {
+		AuthTokenEnv = a.TokenEnv
+	}
+	if a.CredentialHelper != nil {
+		UseCredentialHelper = *a.CredentialHelper
+	}
+	if a.SSHKey != "" {
+		SSHKeyFile = a.SSHKey
+	}
+	if a.SSHUser != "" {
+		SSHUser = a.SSHUser
+	}
+	if a.KnownHosts != "" {
+		KnownHostsFile = a.KnownHosts
+	}
+}
+
+func UpdateRemote(s string) {
+	if r := strings.TrimSpace(s); r != "" {
+		Remote = r
+	}
+}
+
 func UpdateSkipConfirmations(y bool){
 	SkipConfirmations = y
 }
\ No newline at end of file
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	yaml "go.yaml.in/yaml/v4"
+)
+
+// DefaultFile returns the file written by config init: every key commented out at its
+// default, so that only the keys someone uncomments override other layers.
+func DefaultFile() string {
+	return fmt.Sprintf(`# 8stash configuration. Uncomment a key to change its default.
+# Later sources win: ~/.config/8stash/config.yaml, .8stash.yaml in the repository,
+# eightstash.* git config keys, EIGHTSTASH_* environment variables and --remote.
+# Run "8stash config --show-origin" to see the values in effect.
+
+# Prefix of stash branches; a trailing / is added.
+# branch_prefix: %s
+
+# Days after which cleanup deletes a stash.
+# retention_days: %d
+
+# Remote holding the stashes. Defaults to the tracking remote of the branch, else origin.
+# remote: origin
+
+# Where stashes live on the remote: branches or refs.
+# storage: %s
+
+# Whose stashes list, pop, apply and cleanup consider by default: all or mine.
+# scope: %s
+
+# auth:
+#   # Username sent with tokens over HTTPS.
+#   username: x-access-token
+#   # Environment variable holding an HTTPS token. Never put the token itself here.
+#   token_env: %s
+#   # Ask git's credential helpers for HTTPS credentials.
+#   credential_helper: true
+#   # Private key, user and known_hosts file for SSH remotes.
+#   ssh_key: ~/.ssh/id_ed25519
+#   ssh_user: git
+#   known_hosts: ~/.ssh/known_hosts
+
+# naming:
+#   # Stash ids: numeric, uuid, words or sequential.
+#   hash_type: %s
+#   # Exclusive upper bound of numeric ids.
+#   hash_numeric_max_value: %d
+#   # Branch name layout, replacing branch_prefix. Placeholders: {id}, {user}, {email},
+#   # {base} and {date}.
+#   template: wip/{user}/{id}
+`, builtin.CustomBranchPrefix, builtin.RetentionDays, builtin.Storage, builtin.Scope,
+		builtin.Auth.TokenEnv, builtin.Naming.HashType, builtin.Naming.Range)
+}
+
+// WriteDefaultFile writes DefaultFile to path, creating its directory. An existing file
+// is only replaced with force.
+func WriteDefaultFile(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(DefaultFile()), 0o644)
+}
+
+// SetFileKey sets key to value in the config file at path, creating the file if needed.
+// Comments and other keys are kept. Nothing is written if the result would be invalid.
+func SetFileKey(path, key, value string) error {
+	var parsed YamlConfig
+	if err := SetKey(&parsed, key, value); err != nil {
+		return err
+	}
+	// Write ints and bools the way they are read back, e.g. TRUE as true.
+	value, _ = GetKey(&parsed, key)
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("something went wrong reading file %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	var out []byte
+	if len(doc.Content) == 0 {
+		// Empty or comments only, like a fresh config init: keep the text and append.
+		root := &yaml.Node{Kind: yaml.MappingNode}
+		setNode(root, strings.Split(key, "."), scalarNode(&parsed, key, value))
+		encoded, err := encodeNode(root)
+		if err != nil {
+			return err
+		}
+		if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+			b = append(b, '\n')
+		}
+		out = append(b, encoded...)
+	} else {
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s: config must be a mapping of keys to values", path)
+		}
+		setNode(root, strings.Split(key, "."), scalarNode(&parsed, key, value))
+		if out, err = encodeNode(&doc); err != nil {
+			return err
+		}
+	}
+
+	if problems := decodeConfig(path, out).check(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// setNode sets the value at path below a mapping node, adding the mappings on the way.
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			// keep the comments around the old value
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+		child := mapping.Content[i+1]
+		if child.Kind != yaml.MappingNode {
+			// e.g. an empty "naming:" section
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			mapping.Content[i+1] = child
+		}
+		setNode(child, path[1:], value)
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: path[0]}
+	if len(path) == 1 {
+		mapping.Content = append(mapping.Content, key, value)
+		return
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, key, child)
+	setNode(child, path[1:], value)
+}
+
+// scalarNode tags value with the type of key, so that strings such as "123" stay quoted.
+func scalarNode(cfg *YamlConfig, key, value string) *yaml.Node {
+	tag := "!!str"
+	v, _ := field(cfg, key)
+	switch v.Kind() {
+	case reflect.Int:
+		tag = "!!int"
+	case reflect.Pointer:
+		tag = "!!bool"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func encodeNode(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultFile_MentionsEveryKey(t *testing.T) {
+	// Act
+	content := DefaultFile()
+
+	// Assert
+	for _, key := range Keys() {
+		name := key[strings.LastIndex(key, ".")+1:]
+		assert.Regexp(t, `(?m)^#\s+`+name+`:`, content, "key %s should be documented", key)
+	}
+}
+
+func TestWriteDefaultFile_RefusesToOverwriteWithoutForce(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "8stash", "config.yaml")
+
+	// Act
+	errCreate := WriteDefaultFile(path, false)
+	errExisting := WriteDefaultFile(path, false)
+	errForced := WriteDefaultFile(path, true)
+
+	// Assert
+	require.NoError(t, errCreate)
+	assert.ErrorContains(t, errExisting, "already exists")
+	assert.NoError(t, errForced)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFile(), string(data))
+}
+
+func TestSetFileKey_KeepsCommentsAndAddsNestedKeys(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), ConfigName)
+	require.NoError(t, os.WriteFile(path, []byte("# team settings\nretention_days: 7 # a week\n"), 0o644))
+
+	// Act
+	errDays := SetFileKey(path, "retention_days", " 14 ")
+	errHash := SetFileKey(path, "naming.hash_type", "words")
+	errRemote := SetFileKey(path, "remote", "123")
+
+	// Assert
+	require.NoError(t, errDays)
+	require.NoError(t, errHash)
+	require.NoError(t, errRemote)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# team settings\nretention_days: 14 # a week\nnaming:\n  hash_type: words\nremote: \"123\"\n", string(data))
+}
+
+func TestSetFileKey_AppendsToCommentedDefaultFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), ConfigName)
+	require.NoError(t, WriteDefaultFile(path, false))
+
+	// Act
+	err := SetFileKey(path, "scope", "mine")
+
+	// Assert
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFile()+"scope: mine\n", string(data))
+}
+
+func TestSetFileKey_InvalidValue_LeavesFileUnchanged(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), ConfigName)
+	require.NoError(t, os.WriteFile(path, []byte("scope: mine\n"), 0o644))
+
+	// Act
+	errScope := SetFileKey(path, "scope", "team")
+	errDays := SetFileKey(path, "retention_days", "soon")
+	errKey := SetFileKey(path, "naming.colour", "red")
+
+	// Assert
+	assert.ErrorContains(t, errScope, path+":1: scope must be either all or mine")
+	assert.ErrorContains(t, errDays, "retention_days must be a number")
+	assert.ErrorContains(t, errKey, `unknown config key "naming.colour"`)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "scope: mine\n", string(data))
+}
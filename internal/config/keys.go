@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Keys returns the dotted names of all configuration keys, e.g. naming.hash_type,
+// in the order they appear in YamlConfig.
+func Keys() []string {
+	var keys []string
+	collectKeys(reflect.TypeOf(YamlConfig{}), "", &keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := prefix + yamlName(f)
+		if f.Type.Kind() == reflect.Struct {
+			collectKeys(f.Type, name+".", keys)
+			continue
+		}
+		*keys = append(*keys, name)
+	}
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return name
+}
+
+// field returns the field of cfg holding key.
+func field(cfg *YamlConfig, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) == part {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+	if v.Kind() == reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("unknown config key %q", key)
+	}
+	return v, nil
+}
+
+// GetKey formats the value of key in cfg; an unset flag is empty.
+func GetKey(cfg *YamlConfig, key string) (string, error) {
+	v, err := field(cfg, key)
+	if err != nil {
+		return "", err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int:
+		return strconv.Itoa(int(v.Int())), nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "", nil
+		}
+		return strconv.FormatBool(v.Elem().Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported config key %q", key)
+}
+
+// SetKey parses value into key of cfg.
+func SetKey(cfg *YamlConfig, key, value string) error {
+	v, err := field(cfg, key)
+	if err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Pointer:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", key, value)
+		}
+		v.Set(reflect.ValueOf(&b))
+	default:
+		return fmt.Errorf("unsupported config key %q", key)
+	}
+	return nil
+}
+
+// copyKey copies the value of key from src to dst.
+func copyKey(dst, src *YamlConfig, key string) {
+	d, _ := field(dst, key)
+	s, _ := field(src, key)
+	d.Set(s)
+}
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	gitconfig "github.com/go-git/go-git/v6/config"
+	gitformat "github.com/go-git/go-git/v6/plumbing/format/config"
+)
+
+// EnvPrefix starts the environment variables that override config keys,
+// e.g. EIGHTSTASH_NAMING_HASH_TYPE for naming.hash_type.
+const EnvPrefix = "EIGHTSTASH_"
+
+// gitConfigSection holds config keys in git config, e.g. eightstash.naming.hash-type.
+// It is spelled out because go-git cannot parse section names starting with a digit and
+// would fail to read a repository config holding an [8stash] section at all.
+const gitConfigSection = "eightstash"
+
+// OriginDefault is reported for keys that no layer sets.
+const OriginDefault = "default"
+
+// Setting is the effective value of a config key and the layer it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+// builtin holds the built-in defaults, taken before any configuration is applied.
+var builtin = currentDefaults()
+
+var effective, origins = builtin, map[string]string{}
+
+func currentDefaults() YamlConfig {
+	helper := UseCredentialHelper
+	cfg := YamlConfig{
+		CustomBranchPrefix: strings.TrimSuffix(BranchPrefix, "/"),
+		RetentionDays:      CleanUpTimeInDays,
+		Storage:            Storage,
+		Scope:              DefaultScope,
+	}
+	cfg.Auth.CredentialHelper = &helper
+	cfg.Auth.TokenEnv = AuthTokenEnv
+	cfg.Naming.HashType = NamingHashType
+	cfg.Naming.Range = HashRange
+	return cfg
+}
+
+// layer is one source of configuration, applied over the layers before it.
+type layer struct {
+	cfg *YamlConfig
+	// set holds the keys the layer sets, including those set to their zero value.
+	set map[string]bool
+	// origin names where a key came from, e.g. file:<path> or env:<variable>.
+	origin func(key string) string
+	// file is set for config files, to point problems at the line of their key.
+	file *configFile
+}
+
+// setKey parses value into key of the layer and marks the key as set.
+func (l layer) setKey(key, value string) error {
+	if err := SetKey(l.cfg, key, value); err != nil {
+		return err
+	}
+	l.set[key] = true
+	return nil
+}
+
+// locate points a problem with a key of this layer at where the key was set.
+func (l layer) locate(p Problem) Problem {
+	if l.file != nil {
+		return l.file.locate(p)
+	}
+	p.Source = l.origin(p.Key)
+	return p
+}
+
+// Load applies the configuration layers in order: built-in defaults, the user config in
+// $XDG_CONFIG_HOME/8stash/config.yaml, .8stash.yaml in the repository root, git config
+// eightstash.* keys, EIGHTSTASH_* environment variables and finally the global flags that
+// override a key, given by key. Only --remote does so; command flags such as cleanup
+// --days are applied by their command.
+// Any problem in any layer fails the load with a *ValidationError.
+func Load(flags map[string]string) error {
+	merged, loaded, problems, err := resolve(flags)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	UpdateApplicationConfiguration(&merged)
+	effective, origins = merged, loaded
+	return nil
+}
+
+// Validate checks the same layers as Load and returns every problem found in them.
+func Validate(flags map[string]string) ([]Problem, error) {
+	_, _, problems, err := resolve(flags)
+	return problems, err
+}
+
+// resolve merges the layers over the built-in defaults. It returns the origin of every
+// key a layer sets and the problems found on the way.
+func resolve(flags map[string]string) (YamlConfig, map[string]string, []Problem, error) {
+	layers, problems, err := configLayers(flags)
+	if err != nil {
+		return YamlConfig{}, nil, nil, err
+	}
+
+	merged := builtin
+	from := map[string]layer{}
+	for _, l := range layers {
+		for _, key := range Keys() {
+			if l.set[key] {
+				copyKey(&merged, l.cfg, key)
+				from[key] = l
+			}
+		}
+	}
+
+	merged.sanitize()
+	for _, p := range merged.problems() {
+		if l, ok := from[p.Key]; ok {
+			p = l.locate(p)
+		}
+		problems = append(problems, p)
+	}
+	sortProblems(problems)
+
+	loaded := map[string]string{}
+	for key, l := range from {
+		loaded[key] = l.origin(key)
+	}
+	return merged, loaded, problems, nil
+}
+
+// Settings returns every config key with its effective value and origin as of the last Load.
+func Settings() []Setting {
+	var settings []Setting
+	for _, key := range Keys() {
+		value, _ := GetKey(&effective, key)
+		origin := origins[key]
+		if origin == "" {
+			origin = OriginDefault
+		}
+		settings = append(settings, Setting{Key: key, Value: value, Origin: origin})
+	}
+	return settings
+}
+
+func configLayers(flags map[string]string) ([]layer, []Problem, error) {
+	var layers []layer
+	var problems []Problem
+	for _, path := range []string{UserConfigPath(), RepoConfigPath()} {
+		if path == "" {
+			continue
+		}
+		f, err := readConfigFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if f != nil {
+			origin := "file:" + path
+			layers = append(layers, layer{cfg: f.cfg, set: f.set, origin: func(string) string { return origin }, file: f})
+			problems = append(problems, f.problems...)
+		}
+	}
+
+	gitCfg, gitProblems, err := gitConfigLayer()
+	if err != nil {
+		return nil, nil, err
+	}
+	envCfg, envProblems := envLayer()
+	flagCfg, flagProblems := flagLayer(flags)
+	problems = append(problems, gitProblems...)
+	problems = append(problems, envProblems...)
+	problems = append(problems, flagProblems...)
+	return append(layers, gitCfg, envCfg, flagCfg), problems, nil
+}
+
+// UserConfigPath returns $XDG_CONFIG_HOME/8stash/config.yaml, defaulting to ~/.config.
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "8stash", "config.yaml")
+}
+
+// RepoConfigPath returns .8stash.yaml in the root of the repository containing the
+// working directory, or in the working directory outside of a repository.
+func RepoConfigPath() string {
+	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ConfigName
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return ConfigName
+	}
+	return filepath.Join(wt.Filesystem.Root(), ConfigName)
+}
+
+// gitConfigLayer reads eightstash.* keys from the system, global and repository git config,
+// later scopes winning like in git. Git does not allow underscores in key names, so
+// naming.hash_type is written as eightstash.naming.hash-type.
+func gitConfigLayer() (layer, []Problem, error) {
+	cfg := &YamlConfig{}
+	names := map[string]string{}
+	l := layer{cfg: cfg, set: map[string]bool{}, origin: func(key string) string { return "git-config:" + names[key] }}
+
+	scopes, err := gitConfigScopes()
+	if err != nil {
+		return l, nil, err
+	}
+	var keys []string
+	values := map[string]string{}
+	for _, scope := range scopes {
+		for _, section := range scope.Raw.Sections {
+			if !section.IsName(gitConfigSection) {
+				continue
+			}
+			add := func(prefix string, options gitformat.Options) {
+				for _, opt := range options {
+					name := gitConfigSection + "." + prefix + opt.Key
+					key := strings.ToLower(strings.ReplaceAll(prefix+opt.Key, "-", "_"))
+					if _, seen := values[key]; !seen {
+						keys = append(keys, key)
+					}
+					names[key], values[key] = name, opt.Value
+				}
+			}
+			add("", section.Options)
+			for _, sub := range section.Subsections {
+				add(sub.Name+".", sub.Options)
+			}
+		}
+	}
+
+	var problems []Problem
+	for _, key := range keys {
+		if err := l.setKey(key, values[key]); err != nil {
+			problems = append(problems, l.locate(Problem{Key: key, Message: err.Error()}))
+		}
+	}
+	return l, problems, nil
+}
+
+// gitConfigScopes reads the system, global and repository git config. They are read
+// one by one because go-git's merged config (ConfigScoped) keeps only the raw sections
+// of the repository config, which would lose eightstash.* keys set with --global.
+func gitConfigScopes() ([]*gitconfig.Config, error) {
+	var scopes []*gitconfig.Config
+	for _, scope := range []gitconfig.Scope{gitconfig.SystemScope, gitconfig.GlobalScope} {
+		cfg, err := gitconfig.LoadConfig(scope)
+		if err != nil {
+			return nil, fmt.Errorf("read git config: %w", err)
+		}
+		scopes = append(scopes, cfg)
+	}
+
+	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return scopes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read git config: %w", err)
+	}
+	local, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("read git config: %w", err)
+	}
+	return append(scopes, local), nil
+}
+
+// envAliases are shorter variables for some keys. The regular variable wins when both are set.
+var envAliases = map[string]string{"auth.ssh_key": SSHKeyEnvVar}
+
+// EnvVar returns the environment variable overriding key.
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envLayer reads EIGHTSTASH_* variables. A variable that is set but empty puts its key
+// back to the built-in default, e.g. EIGHTSTASH_REMOTE= undoes a remote from a file.
+func envLayer() (layer, []Problem) {
+	names := map[string]string{}
+	l := layer{cfg: &YamlConfig{}, set: map[string]bool{}, origin: func(key string) string { return "env:" + names[key] }}
+	var problems []Problem
+	for _, key := range Keys() {
+		name := EnvVar(key)
+		value, ok := os.LookupEnv(name)
+		if alias, has := envAliases[key]; !ok && has {
+			name = alias
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			continue
+		}
+		names[key] = name
+		if value == "" {
+			defaults := builtin
+			copyKey(l.cfg, &defaults, key)
+			l.set[key] = true
+			continue
+		}
+		if err := l.setKey(key, value); err != nil {
+			problems = append(problems, l.locate(Problem{Key: key, Message: err.Error()}))
+		}
+	}
+	return l, problems
+}
+
+func flagLayer(flags map[string]string) (layer, []Problem) {
+	l := layer{cfg: &YamlConfig{}, set: map[string]bool{}, origin: func(key string) string { return "flag:--" + key }}
+	var problems []Problem
+	for key, value := range flags {
+		if value == "" {
+			continue
+		}
+		if err := l.setKey(key, value); err != nil {
+			problems = append(problems, l.locate(Problem{Key: key, Message: err.Error()}))
+		}
+	}
+	return l, problems
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/test"
+)
+
+// isolateLayers keeps the user config and 8stash environment of whoever runs the tests
+// out of Load, and restores the package variables it sets.
+func isolateLayers(t *testing.T) string {
+	t.Helper()
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("HOME", t.TempDir())
+	for _, key := range Keys() {
+		// Setenv restores the variable after the test; an empty one would reset the key.
+		t.Setenv(EnvVar(key), "")
+		require.NoError(t, os.Unsetenv(EnvVar(key)))
+	}
+	for _, alias := range envAliases {
+		t.Setenv(alias, "")
+		require.NoError(t, os.Unsetenv(alias))
+	}
+
+	orig := currentDefaults()
+	origTemplate, origEffective, origOrigins := NamingTemplate, effective, origins
+	t.Cleanup(func() {
+		BranchPrefix = orig.CustomBranchPrefix + "/"
+		CleanUpTimeInDays = orig.RetentionDays
+		Storage = orig.Storage
+		DefaultScope = orig.Scope
+		UseCredentialHelper = *orig.Auth.CredentialHelper
+		NamingHashType = orig.Naming.HashType
+		HashRange = orig.Naming.Range
+		NamingTemplate = origTemplate
+		AuthTokenEnv = orig.Auth.TokenEnv
+		Remote, AuthUsername, SSHKeyFile, SSHUser, KnownHostsFile = "", "", "", "", ""
+		effective, origins = origEffective, origOrigins
+	})
+	return xdg
+}
+
+func writeUserConfig(t *testing.T, xdg, content string) string {
+	t.Helper()
+	path := filepath.Join(xdg, "8stash", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// gitConfig sets a key such as eightstash.naming.hash-type in the config of a repository.
+func gitConfig(t *testing.T, repoPath, name, value string) {
+	t.Helper()
+	repo, err := git.PlainOpen(repoPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	parts := strings.Split(name, ".")
+	section := cfg.Raw.Section(parts[0])
+	if len(parts) == 3 {
+		section.Subsection(parts[1]).SetOption(parts[2], value)
+	} else {
+		section.SetOption(parts[1], value)
+	}
+	require.NoError(t, repo.SetConfig(cfg))
+}
+
+func originOf(key string) string {
+	for _, s := range Settings() {
+		if s.Key == key {
+			return s.Origin
+		}
+	}
+	return ""
+}
+
+func TestLoad_LaterLayersOverrideEarlierOnes(t *testing.T) {
+	// Arrange
+	xdg := isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	userPath := writeUserConfig(t, xdg, "retention_days: 10\nscope: mine\nremote: fork\nnaming:\n  hash_type: uuid\n")
+	repoPath := filepath.Join(localPath, ConfigName)
+	require.NoError(t, os.WriteFile(repoPath, []byte("retention_days: 20\nremote: upstream\n"), 0o644))
+	gitConfig(t, localPath, "eightstash.remote", "team")
+	gitConfig(t, localPath, "eightstash.naming.hash-type", "words")
+	t.Setenv("EIGHTSTASH_REMOTE", "mirror")
+
+	// Act
+	err := Load(map[string]string{"storage": "refs"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ScopeMine, DefaultScope)
+	assert.Equal(t, 20, CleanUpTimeInDays)
+	assert.Equal(t, HashWords, NamingHashType)
+	assert.Equal(t, "mirror", Remote)
+	assert.Equal(t, StorageRefs, Storage)
+
+	assert.Equal(t, "file:"+userPath, originOf("scope"))
+	assert.Equal(t, "file:"+repoPath, originOf("retention_days"))
+	assert.Equal(t, "git-config:eightstash.naming.hash-type", originOf("naming.hash_type"))
+	assert.Equal(t, "env:EIGHTSTASH_REMOTE", originOf("remote"))
+	assert.Equal(t, "flag:--storage", originOf("storage"))
+	assert.Equal(t, OriginDefault, originOf("branch_prefix"))
+}
+
+func TestLoad_ZeroValuesOverrideEarlierLayers(t *testing.T) {
+	// Arrange
+	xdg := isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	writeUserConfig(t, xdg, "retention_days: 10\nremote: fork\n")
+	repoPath := filepath.Join(localPath, ConfigName)
+	require.NoError(t, os.WriteFile(repoPath, []byte("retention_days: 0\n"), 0o644))
+	t.Setenv("EIGHTSTASH_REMOTE", "")
+
+	// Act
+	err := Load(nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, builtin.RetentionDays, CleanUpTimeInDays, "0 stands for the default retention")
+	assert.Equal(t, "", Remote)
+	assert.Equal(t, "file:"+repoPath, originOf("retention_days"))
+	assert.Equal(t, "env:EIGHTSTASH_REMOTE", originOf("remote"))
+}
+
+func TestLoad_SSHKeyAlias_ReportsItsVariable(t *testing.T) {
+	// Arrange
+	xdg := isolateLayers(t)
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	writeUserConfig(t, xdg, "auth:\n  ssh_key: ~/.ssh/from_file\n")
+	t.Setenv(SSHKeyEnvVar, "~/.ssh/from_alias")
+
+	// Act
+	aliasErr := Load(nil)
+	aliasKey, aliasOrigin := SSHKeyFile, originOf("auth.ssh_key")
+	t.Setenv("EIGHTSTASH_AUTH_SSH_KEY", "~/.ssh/from_env")
+	envErr := Load(nil)
+
+	// Assert
+	require.NoError(t, aliasErr)
+	assert.Equal(t, "~/.ssh/from_alias", aliasKey)
+	assert.Equal(t, "env:"+SSHKeyEnvVar, aliasOrigin)
+	require.NoError(t, envErr)
+	assert.Equal(t, "~/.ssh/from_env", SSHKeyFile)
+	assert.Equal(t, "env:EIGHTSTASH_AUTH_SSH_KEY", originOf("auth.ssh_key"))
+	assert.Equal(t, TokenEnvVar, AuthTokenEnv, "tokens are read from EIGHTSTASH_TOKEN by default")
+}
+
+func TestLoad_GitConfig_RepositoryOverridesGlobal(t *testing.T) {
+	// Arrange
+	xdg := isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	global := filepath.Join(xdg, "git", "config")
+	require.NoError(t, os.MkdirAll(filepath.Dir(global), 0o755))
+	require.NoError(t, os.WriteFile(global, []byte("[eightstash]\n\tscope = mine\n\tretention-days = 5\n"), 0o644))
+	gitConfig(t, localPath, "eightstash.retention-days", "9")
+	gitConfig(t, localPath, "eightstash.naming.hash-type", "words")
+
+	// Act
+	err := Load(nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ScopeMine, DefaultScope)
+	assert.Equal(t, 9, CleanUpTimeInDays)
+	assert.Equal(t, HashWords, NamingHashType)
+	assert.Equal(t, "git-config:eightstash.scope", originOf("scope"))
+	assert.Equal(t, "git-config:eightstash.naming.hash-type", originOf("naming.hash_type"))
+}
+
+func TestLoad_MalformedGitConfig_ReturnsError(t *testing.T) {
+	// Arrange
+	isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, ".git", "config"), []byte("[core\n\tbare = false\n"), 0o644))
+
+	// Act
+	err := Load(nil)
+
+	// Assert
+	assert.ErrorContains(t, err, "read git config")
+}
+
+func TestLoad_FindsRepoConfigFromSubdirectory(t *testing.T) {
+	// Arrange
+	isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, ConfigName), []byte("branch_prefix: wip\n"), 0o644))
+	sub := filepath.Join(localPath, "src", "pkg")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	require.NoError(t, os.Chdir(sub))
+
+	// Act
+	err := Load(nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "wip/", BranchPrefix)
+}
+
+func TestLoad_InvalidEnvValue_ReturnsError(t *testing.T) {
+	// Arrange
+	isolateLayers(t)
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	t.Setenv("EIGHTSTASH_RETENTION_DAYS", "a week")
+
+	// Act
+	err := Load(nil)
+
+	// Assert
+	assert.ErrorContains(t, err, "EIGHTSTASH_RETENTION_DAYS")
+	assert.ErrorContains(t, err, "retention_days must be a number")
+}
+
+func TestKeys_ListsNestedKeysDotted(t *testing.T) {
+	// Act
+	keys := Keys()
+
+	// Assert
+	assert.Contains(t, keys, "branch_prefix")
+	assert.Contains(t, keys, "naming.hash_type")
+	assert.Contains(t, keys, "auth.credential_helper")
+	assert.NotContains(t, keys, "naming")
+}
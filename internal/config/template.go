@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Placeholders that naming.template may contain.
+const (
+	PlaceholderID    = "id"
+	PlaceholderUser  = "user"
+	PlaceholderEmail = "email"
+	PlaceholderBase  = "base"
+	PlaceholderDate  = "date"
+)
+
+// PlaceholderPattern matches a placeholder such as {user} in naming.template.
+var PlaceholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+var knownPlaceholders = []string{PlaceholderID, PlaceholderUser, PlaceholderEmail, PlaceholderBase, PlaceholderDate}
+
+// validateTemplate checks that a branch name template has a fixed prefix, one {id}
+// and only known placeholders.
+func validateTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if TemplatePrefix(template) == "" {
+		return fmt.Errorf("naming.template must start with a fixed prefix such as 'wip/', got %q", template)
+	}
+	ids := 0
+	for _, m := range PlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		switch name := m[1]; {
+		case name == PlaceholderID:
+			ids++
+		case !slices.Contains(knownPlaceholders, name):
+			return fmt.Errorf("naming.template has unknown placeholder {%s}; use one of {%s}", name, strings.Join(knownPlaceholders, "}, {"))
+		}
+	}
+	if ids != 1 {
+		return fmt.Errorf("naming.template must contain {%s} exactly once, got %q", PlaceholderID, template)
+	}
+	return nil
+}
+
+// TemplatePrefix returns the fixed part of a template before its first placeholder.
+// Stashes are fetched and listed below it.
+func TemplatePrefix(template string) string {
+	prefix, _, _ := strings.Cut(template, "{")
+	return prefix
+}
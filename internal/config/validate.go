@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	yaml "go.yaml.in/yaml/v4"
+)
+
+// Problem is one invalid setting, located in the layer it came from.
+type Problem struct {
+	// Source is the config file or the origin of the setting, e.g. env:EIGHTSTASH_SCOPE.
+	Source string
+	// Line is the line of the key in a config file, 0 if unknown.
+	Line    int
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Source == "":
+		return p.Message
+	case p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", p.Source, p.Line, p.Message)
+	}
+	return p.Source + ": " + p.Message
+}
+
+// ValidationError lists every problem that stopped the configuration from loading.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid config: " + e.Problems[0].String()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config, %d problems:", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  " + p.String())
+	}
+	return b.String()
+}
+
+// configFile is a parsed config file with the line of every key in it.
+type configFile struct {
+	path  string
+	cfg   *YamlConfig
+	lines map[string]int
+	// set holds the keys the file gives a value, which may be the zero value.
+	set      map[string]bool
+	problems []Problem
+}
+
+// decodeConfig parses a config file key by key, so that every unknown key and bad
+// value is reported with its line instead of stopping at the first one.
+func decodeConfig(path string, b []byte) *configFile {
+	f := &configFile{path: path, cfg: &YamlConfig{}, lines: map[string]int{}, set: map[string]bool{}}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		f.problems = append(f.problems, Problem{Source: path, Message: "parsing config: " + err.Error()})
+		return f
+	}
+	if len(doc.Content) == 0 {
+		return f
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		f.problems = append(f.problems, Problem{Source: path, Line: root.Line, Message: "config must be a mapping of keys to values"})
+		return f
+	}
+	f.decodeMapping(root, "")
+	return f
+}
+
+func (f *configFile) decodeMapping(n *yaml.Node, prefix string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		key := prefix + k.Value
+		f.lines[key] = k.Line
+		switch {
+		case isSection(key):
+			if v.Kind == yaml.MappingNode {
+				f.decodeMapping(v, key+".")
+			} else if v.Tag != "!!null" {
+				f.addProblem(key, fmt.Sprintf("%s must hold keys such as %s", key, sectionExample(key)))
+			}
+		case !slices.Contains(Keys(), key):
+			f.addProblem(key, fmt.Sprintf("unknown key %s", key))
+		case v.Kind != yaml.ScalarNode:
+			f.addProblem(key, fmt.Sprintf("%s must be a single value", key))
+		case v.Tag == "!!null":
+			// An empty value leaves the key unset.
+		default:
+			if err := SetKey(f.cfg, key, v.Value); err != nil {
+				f.addProblem(key, err.Error())
+				continue
+			}
+			f.set[key] = true
+		}
+	}
+}
+
+func (f *configFile) addProblem(key, message string) {
+	f.problems = append(f.problems, f.locate(Problem{Key: key, Message: message}))
+}
+
+// check sanitizes the config of the file on its own and returns all of its problems.
+func (f *configFile) check() []Problem {
+	f.cfg.sanitize()
+	problems := slices.Clone(f.problems)
+	for _, p := range f.cfg.problems() {
+		problems = append(problems, f.locate(p))
+	}
+	sortProblems(problems)
+	return problems
+}
+
+// sortProblems orders problems by source and line, so the report does not depend on
+// the order in which keys were checked.
+func sortProblems(problems []Problem) {
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if c := strings.Compare(a.Source, b.Source); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+}
+
+// locate points a problem at the line of its key.
+func (f *configFile) locate(p Problem) Problem {
+	p.Source, p.Line = f.path, f.lines[p.Key]
+	return p
+}
+
+// isSection reports whether key groups other keys, like naming.
+func isSection(key string) bool {
+	return sectionExample(key) != ""
+}
+
+func sectionExample(section string) string {
+	for _, key := range Keys() {
+		if strings.HasPrefix(key, section+".") {
+			return key
+		}
+	}
+	return ""
+}
+
+// problems lists every invalid setting of a sanitized config.
+func (c *YamlConfig) problems() []Problem {
+	var problems []Problem
+	add := func(key, format string, args ...any) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.RetentionDays < 0 {
+		add("retention_days", "retention_days must be >= 0")
+	}
+
+	if c.Storage != "" && c.Storage != StorageBranches && c.Storage != StorageRefs {
+		add("storage", "storage must be either %s or %s", StorageBranches, StorageRefs)
+	}
+
+	if !slices.Contains([]HashType{HashNumeric, HashUUID, HashWords, HashSequential}, c.Naming.HashType) {
+		add("naming.hash_type", "naming.hash_type must be numeric, uuid, words or sequential, got %q", c.Naming.HashType)
+	}
+
+	if c.Scope != "" && c.Scope != ScopeAll && c.Scope != ScopeMine {
+		add("scope", "scope must be either %s or %s", ScopeAll, ScopeMine)
+	}
+
+	if err := validateTemplate(c.Naming.Template); err != nil {
+		add("naming.template", "%s", err)
+	}
+
+	if c.Naming.HashType == HashNumeric {
+		if c.Naming.Range <= MinNumericRange {
+			add("naming.hash_numeric_max_value", "naming.hash_numeric_max_value must be > %d", MinNumericRange)
+		}
+		if c.Naming.Range > MaxNumericrange {
+			add("naming.hash_numeric_max_value", "naming.hash_numeric_max_value must be at most %d; consider hash_type uuid", MaxNumericrange)
+		}
+	}
+
+	return problems
+}
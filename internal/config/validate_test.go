@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/test"
+)
+
+func TestDecodeConfig_ReportsEveryProblemWithItsLine(t *testing.T) {
+	// Arrange
+	content := `branch_prefix: wip
+retention_days: soon
+scope: team
+naming:
+  hash_typ: words
+auth: true
+`
+
+	// Act
+	problems := decodeConfig("cfg.yaml", []byte(content)).check()
+
+	// Assert
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	assert.Equal(t, []string{
+		`cfg.yaml:2: retention_days must be a number, got "soon"`,
+		`cfg.yaml:3: scope must be either all or mine`,
+		`cfg.yaml:5: unknown key naming.hash_typ`,
+		`cfg.yaml:6: auth must hold keys such as auth.username`,
+	}, lines)
+}
+
+func TestDecodeConfig_EmptyValuesAndCommentsOnly_NoProblems(t *testing.T) {
+	// Act
+	empty := decodeConfig("cfg.yaml", []byte("remote:\nnaming:\n")).check()
+	comments := decodeConfig("cfg.yaml", []byte(DefaultFile())).check()
+
+	// Assert
+	assert.Empty(t, empty)
+	assert.Empty(t, comments)
+}
+
+func TestValidate_ReportsProblemsOfEveryLayer(t *testing.T) {
+	// Arrange
+	isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repoPath := filepath.Join(localPath, ConfigName)
+	require.NoError(t, os.WriteFile(repoPath, []byte("retention_days: 7\nstorage: tape\nnaming:\n  hash_type: dice\nscope: team\n"), 0o644))
+	t.Setenv("EIGHTSTASH_SCOPE", "team")
+
+	// Act
+	problems, err := Validate(map[string]string{"remote": "team"})
+	loadErr := Load(nil)
+
+	// Assert
+	require.NoError(t, err)
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	assert.Equal(t, []string{
+		repoPath + ":2: storage must be either branches or refs",
+		repoPath + ":4: naming.hash_type must be numeric, uuid, words or sequential, got \"dice\"",
+		"env:EIGHTSTASH_SCOPE: scope must be either all or mine",
+	}, lines)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, loadErr, &validationErr)
+	assert.Equal(t, problems, validationErr.Problems)
+	assert.NotEqual(t, 7, CleanUpTimeInDays, "an invalid config must not be applied")
+}
@@ -3,10 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
-
-	yaml "go.yaml.in/yaml/v4"
 )
 
 type HashType string
@@ -15,20 +12,76 @@ type HashType string
 const (
 	HashNumeric HashType = "numeric"
 	HashUUID    HashType = "uuid"
+	// HashWords generates adjective-noun ids such as brave-otter.
+	HashWords HashType = "words"
+	// HashSequential numbers the stashes of a repository 1, 2, 3, ...
+	HashSequential HashType = "sequential"
+)
+
+// StorageMode decides where stashes live on the remote.
+type StorageMode string
+
+const (
+	// StorageBranches stores every stash as a branch under refs/heads/<prefix><id>.
+	StorageBranches StorageMode = "branches"
+	// StorageRefs stores stashes under refs/<prefix><id>, out of sight of branch lists.
+	StorageRefs StorageMode = "refs"
+)
+
+// ScopeMode decides whose stashes list, pop and cleanup look at by default.
+type ScopeMode string
+
+const (
+	// ScopeAll shows the stashes of everyone on the remote.
+	ScopeAll ScopeMode = "all"
+	// ScopeMine shows only the stashes authored by the current git user.
+	ScopeMine ScopeMode = "mine"
 )
 
+// AuthConfig configures credentials for remotes. Tokens and passphrases are never
+// stored in the file itself, only the name of the environment variable holding one.
+type AuthConfig struct {
+	Username         string `yaml:"username"`
+	TokenEnv         string `yaml:"token_env"`
+	CredentialHelper *bool  `yaml:"credential_helper"`
+	SSHKey           string `yaml:"ssh_key"`
+	SSHUser          string `yaml:"ssh_user"`
+	KnownHosts       string `yaml:"known_hosts"`
+}
+
 type YamlConfig struct {
-	CustomBranchPrefix string `yaml:"branch_prefix"`
-	RetentionDays      int    `yaml:"retention_days"`
+	CustomBranchPrefix string      `yaml:"branch_prefix"`
+	RetentionDays      int         `yaml:"retention_days"`
+	Remote             string      `yaml:"remote"`
+	Storage            StorageMode `yaml:"storage"`
+	Scope              ScopeMode   `yaml:"scope"`
+	Auth               AuthConfig  `yaml:"auth"`
 	Naming             struct {
 		HashType HashType `yaml:"hash_type"`
 		Range    int      `yaml:"hash_numeric_max_value"` // this is maxvalue so not a diget count
+		// Template lays out stash branch names, e.g. wip/{user}/{base}/{id}. It replaces branch_prefix.
+		Template string `yaml:"template"`
 	} `yaml:"naming"`
 }
 
 func (c *YamlConfig) sanitize() {
 	c.CustomBranchPrefix = strings.TrimSpace(c.CustomBranchPrefix)
     c.CustomBranchPrefix = strings.Trim(c.CustomBranchPrefix, "/")
+    c.Remote = strings.TrimSpace(c.Remote)
+    c.Storage = StorageMode(strings.ToLower(strings.TrimSpace(string(c.Storage))))
+    if c.Storage == "" {
+        c.Storage = StorageBranches
+    }
+    c.Scope = ScopeMode(strings.ToLower(strings.TrimSpace(string(c.Scope))))
+    if c.Scope == "" {
+        c.Scope = ScopeAll
+    }
+    c.Auth.Username = strings.TrimSpace(c.Auth.Username)
+    c.Auth.TokenEnv = strings.TrimSpace(c.Auth.TokenEnv)
+    c.Auth.SSHKey = strings.TrimSpace(c.Auth.SSHKey)
+    c.Auth.SSHUser = strings.TrimSpace(c.Auth.SSHUser)
+    c.Auth.KnownHosts = strings.TrimSpace(c.Auth.KnownHosts)
+    c.Naming.Template = strings.TrimSpace(c.Naming.Template)
     if c.Naming.HashType == "" {
         c.Naming.HashType = HashNumeric
     }
@@ -37,54 +90,20 @@ func (c *YamlConfig) sanitize() {
         c.Naming.Range = HashRange
     }
 
-    if c.Naming.HashType == HashNumeric && c.Naming.Range > MaxNumericrange {
-        print("8stash config Numeric range is too big switching to default max range consider using hash_type UUID")
-        c.Naming.Range = MaxNumericrange
-    }
-	
-    if c.Naming.HashType == HashUUID {
+    if c.Naming.HashType == HashUUID || c.Naming.HashType == HashWords || c.Naming.HashType == HashSequential {
         c.Naming.Range = HashRange
     }
 }
 
-func (c *YamlConfig) validate() error {
-	if c.RetentionDays < 0 {
-		return fmt.Errorf("retention_days must be >= 0")
-	}
-
-	if c.Naming.HashType != HashNumeric && c.Naming.HashType != HashUUID {
-		print("hash_type has to be either numeric or uuid, setting config to default numeric")
-		c.Naming.HashType = HashNumeric
-	}
-
-	if c.Naming.HashType == HashNumeric {
-		if c.Naming.Range <= MinNumericRange{
-			return fmt.Errorf("naming.hash_numeric_max_value must be > %s", strconv.Itoa(MinNumericRange))
-		}
-	}
-
-	return nil
-}
-
-func LoadConfig(path string) error {
+// readConfigFile parses a config file; a missing file yields no config and no error.
+// Problems in the file are collected in it rather than returned.
+func readConfigFile(path string) (*configFile, error) {
 	b, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return nil
+		return nil, nil
 	}
 	if err != nil {
-		return fmt.Errorf("something went wrong reading file %w", err)
-	}
-
-	var cfg YamlConfig
-	if err := yaml.Unmarshal(b, &cfg); err != nil {
-		return fmt.Errorf("parsing config: %w", err)
+		return nil, fmt.Errorf("something went wrong reading file %w", err)
 	}
-
-	cfg.sanitize()
-	if err := cfg.validate(); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
-	}
-
-	UpdateApplicationConfiguration(&cfg)
-	return nil
+	return decodeConfig(path, b), nil
 }
@@ -13,7 +13,19 @@ import (
 	"8stash/internal/test"
 )
 
-func TestLoadConfig_MissingFile_NoError_DefaultsUnchanged(t *testing.T) {
+// loadRepoConfig writes content to .8stash.yaml in a new test repository and loads the
+// configuration the way the binary does. It returns the path of the file.
+func loadRepoConfig(t *testing.T, content string) (string, error) {
+	t.Helper()
+	isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	t.Cleanup(cleanup)
+	path := filepath.Join(localPath, ConfigName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path, Load(nil)
+}
+
+func TestLoad_MissingFile_NoError_DefaultsUnchanged(t *testing.T) {
 	// Arrange
 	origPrefix := BranchPrefix
 	origRetention := CleanUpTimeInDays
@@ -22,10 +34,12 @@ func TestLoadConfig_MissingFile_NoError_DefaultsUnchanged(t *testing.T) {
 		CleanUpTimeInDays = origRetention
 	})
 
-	nonExistent := filepath.Join(os.TempDir(), "definitely-does-not-exist-8stash.yaml")
+	isolateLayers(t)
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
 
 	// Act
-	err := LoadConfig(nonExistent)
+	err := Load(nil)
 
 	// Assert
 	require.NoError(t, err)
@@ -33,7 +47,7 @@ func TestLoadConfig_MissingFile_NoError_DefaultsUnchanged(t *testing.T) {
 	assert.Equal(t, origRetention, CleanUpTimeInDays)
 }
 
-func TestLoadConfig_ValidFile_AppliesValues(t *testing.T) {
+func TestLoad_ValidFile_AppliesValues(t *testing.T) {
 	// Arrange
 	origPrefix := BranchPrefix
 	origRetention := CleanUpTimeInDays
@@ -46,11 +60,8 @@ func TestLoadConfig_ValidFile_AppliesValues(t *testing.T) {
 branch_prefix: customprefix
 retention_days: 7
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
 	// Act
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 
 	// Assert
 	require.NoError(t, err)
@@ -58,7 +69,7 @@ retention_days: 7
 	assert.Equal(t, 7, CleanUpTimeInDays)
 }
 
-func TestLoadConfig_SanitizesBranchPrefix_RemovesSlashesAndSpaces(t *testing.T) {
+func TestLoad_SanitizesBranchPrefix_RemovesSlashesAndSpaces(t *testing.T) {
 	// Arrange
 	origPrefix := BranchPrefix
 	t.Cleanup(func() { BranchPrefix = origPrefix })
@@ -66,32 +77,27 @@ func TestLoadConfig_SanitizesBranchPrefix_RemovesSlashesAndSpaces(t *testing.T)
 	content := `
 branch_prefix: " /my-prefix/ "
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
 	// Act
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "my-prefix/", BranchPrefix)
 }
 
-func TestLoadConfig_InvalidYAML_ReturnsError(t *testing.T) {
+func TestLoad_InvalidYAML_ReturnsError(t *testing.T) {
 	// Arrange
 	content := `: ::: not yaml`
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
 
 	// Act
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 
 	// Assert
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "parsing config")
 }
 
-func TestLoadConfig_NegativeRetention_ReturnsError(t *testing.T) {
+func TestLoad_NegativeRetention_ReturnsError(t *testing.T) {
 	// Arrange
 	origRetention := CleanUpTimeInDays
 	t.Cleanup(func() { CleanUpTimeInDays = origRetention })
@@ -99,11 +105,8 @@ func TestLoadConfig_NegativeRetention_ReturnsError(t *testing.T) {
 	content := `
 retention_days: -5
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
 	// Act
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 
 	// Assert
 	require.Error(t, err)
@@ -111,7 +114,7 @@ retention_days: -5
 	assert.ErrorContains(t, err, "retention_days must be >= 0")
 }
 
-func TestLoadConfig_NamingDefaults_WhenMissing(t *testing.T) {
+func TestLoad_NamingDefaults_WhenMissing(t *testing.T) {
 	origPrefix := BranchPrefix
 	origRetention := CleanUpTimeInDays
 	origHashType := NamingHashType
@@ -127,10 +130,7 @@ func TestLoadConfig_NamingDefaults_WhenMissing(t *testing.T) {
 branch_prefix: repo-prefix
 retention_days: 5
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 	require.NoError(t, err)
 
 	assert.Equal(t, "repo-prefix/", BranchPrefix)
@@ -140,7 +140,7 @@ retention_days: 5
 	assert.Equal(t, 9999, HashRange)
 }
 
-func TestLoadConfig_AppliesNumericRangeAndHashType(t *testing.T) {
+func TestLoad_AppliesNumericRangeAndHashType(t *testing.T) {
 	origHashType := NamingHashType
 	origHashRange := HashRange
 	t.Cleanup(func() {
@@ -153,17 +153,14 @@ naming:
   hash_type: numeric
   hash_numeric_max_value: 5000
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 	require.NoError(t, err)
 
 	assert.Equal(t, HashNumeric, NamingHashType)
 	assert.Equal(t, 5000, HashRange)
 }
 
-func TestLoadConfig_UUIDIgnoresRangeAndUsesDefaultHashRange(t *testing.T) {
+func TestLoad_UUIDIgnoresRangeAndUsesDefaultHashRange(t *testing.T) {
 	origHashType := NamingHashType
 	origHashRange := HashRange
 	t.Cleanup(func() {
@@ -176,10 +173,7 @@ naming:
   hash_type: uuid
   hash_numeric_max_value: 2
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
-
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, content)
 	require.NoError(t, err)
 
 	// hash type should be uuid, but effective HashRange should remain default
@@ -187,7 +181,7 @@ naming:
 	assert.Equal(t, 9999, HashRange)
 }
 
-func TestLoadConfig_InvalidHashType_FallsBackToNumericAndAppliesRange(t *testing.T) {
+func TestLoad_WordsHashType(t *testing.T) {
 	origHashType := NamingHashType
 	origHashRange := HashRange
 	t.Cleanup(func() {
@@ -197,20 +191,80 @@ func TestLoadConfig_InvalidHashType_FallsBackToNumericAndAppliesRange(t *testing
 
 	content := `
 naming:
-  hash_type: not-a-type
-  hash_numeric_max_value: 1234
+  hash_type: words
+  hash_numeric_max_value: 500
 `
-	path := test.WriteTempFile(t, content)
-	defer os.Remove(path)
+	_, err := loadRepoConfig(t, content)
+	require.NoError(t, err)
 
-	err := LoadConfig(path)
+	assert.Equal(t, HashWords, NamingHashType)
+	assert.Equal(t, 9999, HashRange)
+}
+
+func TestLoad_NamingTemplate_SetsTemplateAndPrefix(t *testing.T) {
+	origPrefix, origTemplate := BranchPrefix, NamingTemplate
+	t.Cleanup(func() {
+		BranchPrefix, NamingTemplate = origPrefix, origTemplate
+	})
+
+	content := `
+branch_prefix: ignored
+naming:
+  template: " wip/{user}/{base}/{id} "
+`
+	_, err := loadRepoConfig(t, content)
 	require.NoError(t, err)
 
-	assert.Equal(t, HashNumeric, NamingHashType)
-	assert.Equal(t, 1234, HashRange)
+	assert.Equal(t, "wip/{user}/{base}/{id}", NamingTemplate)
+	assert.Equal(t, "wip/", BranchPrefix)
+}
+
+func TestLoad_InvalidNamingTemplate_Error(t *testing.T) {
+	testCases := []struct {
+		template string
+		wantErr  string
+	}{
+		{"wip/{user}", "must contain {id} exactly once"},
+		{"wip/{id}/{id}", "must contain {id} exactly once"},
+		{"wip/{team}/{id}", "unknown placeholder {team}"},
+		{"{user}/{id}", "must start with a fixed prefix"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			_, err := loadRepoConfig(t, "naming:\n  template: \""+tc.template+"\"\n")
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
 }
 
-func TestLoadConfig_EmptyNamedConfig_UsesDefaults(t *testing.T) {
+func TestLoad_InvalidHashType_ReturnsErrorWithLine(t *testing.T) {
+	// Arrange
+	origHashType := NamingHashType
+	origHashRange := HashRange
+	t.Cleanup(func() {
+		NamingHashType = origHashType
+		HashRange = origHashRange
+	})
+
+	content := `
+naming:
+  hash_type: not-a-type
+  hash_numeric_max_value: 1234
+`
+	// Act
+	path, err := loadRepoConfig(t, content)
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, path+":3: naming.hash_type must be numeric, uuid, words or sequential")
+	assert.Equal(t, origHashType, NamingHashType)
+	assert.Equal(t, origHashRange, HashRange)
+}
+
+func TestLoad_EmptyNamedConfig_UsesDefaults(t *testing.T) {
 	origPrefix := BranchPrefix
 	origRetention := CleanUpTimeInDays
 	origHashType := NamingHashType
@@ -222,11 +276,7 @@ func TestLoadConfig_EmptyNamedConfig_UsesDefaults(t *testing.T) {
 		HashRange = origHashRange
 	})
 
-	dir := t.TempDir()
-	path := filepath.Join(dir, ConfigName)
-	require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
-
-	err := LoadConfig(path)
+	_, err := loadRepoConfig(t, "")
 	require.NoError(t, err)
 
 	assert.Equal(t, origPrefix, BranchPrefix)
@@ -242,23 +292,23 @@ func TestValidate_NumericRangeTooSmall_ReturnsError(t *testing.T) {
 	cfg.Naming.Range = 0
 
 	// Act
-	err := cfg.validate()
+	problems := cfg.problems()
 
 	// Assert
-	require.Error(t, err)
-	assert.ErrorContains(t, err, "naming.hash_numeric_max_value must be >")
-	assert.ErrorContains(t, err, strconv.Itoa(MinNumericRange))
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "naming.hash_numeric_max_value must be >")
+	assert.Contains(t, problems[0].Message, strconv.Itoa(MinNumericRange))
 }
 
-func TestLoadConfig_ReadFileError_ReturnsWrappedError(t *testing.T) {
+func TestLoad_ReadFileError_ReturnsWrappedError(t *testing.T) {
 	// Arrange
-	dir := t.TempDir()
-	info, err := os.Stat(dir)
-	require.NoError(t, err)
-	require.True(t, info.IsDir())
+	isolateLayers(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.Mkdir(filepath.Join(localPath, ConfigName), 0o755))
 
 	// Act
-	err = LoadConfig(dir)
+	err := Load(nil)
 
 	// Assert
 	require.Error(t, err)
@@ -266,25 +316,115 @@ func TestLoadConfig_ReadFileError_ReturnsWrappedError(t *testing.T) {
 	assert.True(t, strings.Contains(err.Error(), "is a directory") || strings.Contains(err.Error(), "permission") || strings.Contains(err.Error(), "open"), "wrapped error should contain underlying io error")
 }
 
-func TestSanitize_LargeRange_PrintsWarningAndClamps(t *testing.T) {
+func TestValidate_LargeRange_ReturnsError(t *testing.T) {
+	// Arrange
 	cfg := &YamlConfig{}
 	cfg.Naming.HashType = HashNumeric
 	cfg.Naming.Range = MaxNumericrange + 5
 
-	// capture stderr
-	oldStderr := os.Stderr
-	r, w, err := os.Pipe()
+	// Act
+	cfg.sanitize()
+	problems := cfg.problems()
+
+	// Assert
+	assert.Equal(t, MaxNumericrange+5, cfg.Naming.Range)
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "naming.hash_numeric_max_value must be at most "+strconv.Itoa(MaxNumericrange))
+}
+
+func TestLoad_Storage_AppliesRefs(t *testing.T) {
+	// Arrange
+	origStorage := Storage
+	t.Cleanup(func() { Storage = origStorage })
+
+	content := `
+storage: " Refs "
+`
+	// Act
+	_, err := loadRepoConfig(t, content)
+
+	// Assert
 	require.NoError(t, err)
-	os.Stderr = w
+	assert.Equal(t, StorageRefs, Storage)
+}
 
-	// call sanitize while stderr is captured
-	cfg.sanitize()
+func TestLoad_InvalidStorage_ReturnsError(t *testing.T) {
+	// Arrange
+	origStorage := Storage
+	t.Cleanup(func() { Storage = origStorage })
+
+	content := `
+storage: tags
+`
+	// Act
+	_, err := loadRepoConfig(t, content)
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "storage must be either branches or refs")
+	assert.Equal(t, origStorage, Storage)
+}
+
+func TestLoad_Scope(t *testing.T) {
+	// Arrange
+	origScope := DefaultScope
+	t.Cleanup(func() { DefaultScope = origScope })
+
+	// Act
+	_, errValid := loadRepoConfig(t, "scope: Mine\n")
+	scope := DefaultScope
+	_, errInvalid := loadRepoConfig(t, "scope: team\n")
+
+	// Assert
+	require.NoError(t, errValid)
+	assert.Equal(t, ScopeMine, scope)
+	require.Error(t, errInvalid)
+	assert.ErrorContains(t, errInvalid, "scope must be either all or mine")
+}
+
+func TestLoad_Auth_AppliesSettings(t *testing.T) {
+	// Arrange
+	origUsername, origTokenEnv, origHelper := AuthUsername, AuthTokenEnv, UseCredentialHelper
+	origKey, origSSHUser, origKnownHosts := SSHKeyFile, SSHUser, KnownHostsFile
+	t.Cleanup(func() {
+		AuthUsername, AuthTokenEnv, UseCredentialHelper = origUsername, origTokenEnv, origHelper
+		SSHKeyFile, SSHUser, KnownHostsFile = origKey, origSSHUser, origKnownHosts
+	})
+
+	content := `
+auth:
+  username: " ci-bot "
+  token_env: GITLAB_TOKEN
+  credential_helper: false
+  ssh_key: " ~/.ssh/ci_deploy "
+  ssh_user: deploy
+  known_hosts: ./ci/known_hosts
+`
+	// Act
+	_, err := loadRepoConfig(t, content)
 
-	// restore and read captured output
-	_ = w.Close()
-	_ = r.Close()
-	os.Stderr = oldStderr
+	// Assert
 	require.NoError(t, err)
+	assert.Equal(t, "ci-bot", AuthUsername)
+	assert.Equal(t, "GITLAB_TOKEN", AuthTokenEnv)
+	assert.False(t, UseCredentialHelper)
+	assert.Equal(t, "~/.ssh/ci_deploy", SSHKeyFile)
+	assert.Equal(t, "deploy", SSHUser)
+	assert.Equal(t, "./ci/known_hosts", KnownHostsFile)
+}
+
+func TestLoad_Remote_AppliesTrimmedName(t *testing.T) {
+	// Arrange
+	origRemote := Remote
+	t.Cleanup(func() { Remote = origRemote })
 
-	assert.Equal(t, MaxNumericrange, cfg.Naming.Range)
+	content := `
+remote: " upstream "
+`
+	// Act
+	_, err := loadRepoConfig(t, content)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", Remote)
 }
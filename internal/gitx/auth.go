@@ -0,0 +1,149 @@
+package gitx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/http"
+
+	stashconfig "8stash/internal/config"
+)
+
+// defaultTokenUsername is sent with tokens when no username is configured;
+// token based hosts only check the password.
+const defaultTokenUsername = "x-access-token"
+
+// authCache holds the auth method of every remote url used so far. A command runs
+// several remote operations, and an encrypted key should be decrypted, and its
+// passphrase asked for, only once.
+var authCache = map[string]transport.AuthMethod{}
+
+// remoteAuth returns the credentials for remote operations against remoteName.
+// Every fetch, pull and push goes through here. A nil method means anonymous access.
+func remoteAuth(repo *git.Repository, remoteName string) (transport.AuthMethod, error) {
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("remote %s: %w", remoteName, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	if auth, ok := authCache[urls[0]]; ok {
+		return auth, nil
+	}
+	ep, err := transport.NewEndpoint(urls[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse url of remote %s: %w", remoteName, err)
+	}
+
+	var auth transport.AuthMethod
+	switch ep.Protocol {
+	case "http", "https":
+		auth = httpAuth(ep)
+	case "ssh":
+		if auth, err = newSSHAuth(ep); err != nil {
+			return nil, err
+		}
+	}
+	authCache[urls[0]] = auth
+	return auth, nil
+}
+
+// withAuthMethod names the auth method in errors of remote operations, so a
+// rejected push says which credentials were used.
+func withAuthMethod(err error, auth transport.AuthMethod) error {
+	switch a := auth.(type) {
+	case nil:
+		return fmt.Errorf("%w (no credentials used)", err)
+	case *sshAuth:
+		return fmt.Errorf("%w (auth: %s)", err, a.method)
+	case *http.BasicAuth:
+		return fmt.Errorf("%w (auth: https basic auth as %s)", err, a.Username)
+	default:
+		return fmt.Errorf("%w (auth: %s)", err, auth.Name())
+	}
+}
+
+// httpAuth tries, in order: credentials in the url, the token in the variable named
+// by auth.token_env (EIGHTSTASH_TOKEN by default) and finally git's credential helpers.
+// The token is only sent over https, never in plain text to an http remote.
+func httpAuth(ep *transport.Endpoint) transport.AuthMethod {
+	if ep.User != "" && ep.Password != "" {
+		return &http.BasicAuth{Username: ep.User, Password: ep.Password}
+	}
+	if name := stashconfig.AuthTokenEnv; name != "" && ep.Protocol == "https" {
+		if token := os.Getenv(name); token != "" {
+			return tokenAuth(ep, token)
+		}
+	}
+	if stashconfig.UseCredentialHelper {
+		if username, password, ok := credentialFill(ep); ok {
+			return &http.BasicAuth{Username: username, Password: password}
+		}
+	}
+	return nil
+}
+
+func tokenAuth(ep *transport.Endpoint, token string) transport.AuthMethod {
+	username := stashconfig.AuthUsername
+	if username == "" {
+		username = ep.User
+	}
+	if username == "" {
+		username = defaultTokenUsername
+	}
+	return &http.BasicAuth{Username: username, Password: token}
+}
+
+// credentialFill asks `git credential fill` for credentials, which consults the
+// configured credential helpers. Prompting is disabled so it never blocks.
+func credentialFill(ep *transport.Endpoint) (string, string, bool) {
+	host := ep.Host
+	if ep.Port != 0 {
+		host += ":" + strconv.Itoa(ep.Port)
+	}
+	var input strings.Builder
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\npath=%s\n", ep.Protocol, host, strings.TrimPrefix(ep.Path, "/"))
+	if username := firstNonEmpty(stashconfig.AuthUsername, ep.User); username != "" {
+		fmt.Fprintf(&input, "username=%s\n", username)
+	}
+	input.WriteString("\n")
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input.String())
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", false
+	}
+
+	var username, password string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	return username, password, password != ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
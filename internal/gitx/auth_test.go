@@ -0,0 +1,120 @@
+package gitx
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	stashconfig "8stash/internal/config"
+	"8stash/internal/test"
+)
+
+// setupHTTPRemote serves the test remote over HTTPS with basic auth and points origin at it.
+// It returns the local path, the path of the bare remote and the cleanup function.
+func setupHTTPRemote(t *testing.T, username, password string) (string, string, func()) {
+	t.Helper()
+	localPath, cleanup := test.SetupTestRepo(t)
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	remotePath := cfg.Remotes["origin"].URLs[0]
+	setRemoteURL(t, repo, test.ServeRepoOverHTTPS(t, remotePath, username, password))
+
+	// Keep the environment of the machine running the tests out of the way.
+	t.Setenv(stashconfig.TokenEnvVar, "")
+	clear(authCache)
+	t.Cleanup(func() { clear(authCache) })
+	origUsername, origTokenEnv, origHelper := stashconfig.AuthUsername, stashconfig.AuthTokenEnv, stashconfig.UseCredentialHelper
+	t.Cleanup(func() {
+		stashconfig.AuthUsername, stashconfig.AuthTokenEnv, stashconfig.UseCredentialHelper = origUsername, origTokenEnv, origHelper
+	})
+	return localPath, remotePath, cleanup
+}
+
+func TestHTTPAuth_TokenFromEnvironment(t *testing.T) {
+	// Arrange
+	localPath, remotePath, cleanup := setupHTTPRemote(t, defaultTokenUsername, "s3cret")
+	defer cleanup()
+	t.Setenv(stashconfig.TokenEnvVar, "s3cret")
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	// Act
+	err := StashChangesToNewBranch("8stash/1234", StashOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	remote, err := git.PlainOpen(remotePath)
+	require.NoError(t, err)
+	_, err = remote.Reference(plumbing.NewBranchReferenceName("8stash/1234"), false)
+	assert.NoError(t, err)
+	assert.NoError(t, UpdateRepository())
+}
+
+func TestHTTPAuth_PlainHTTP_DoesNotSendToken(t *testing.T) {
+	// Arrange
+	localPath, remotePath, cleanup := setupHTTPRemote(t, defaultTokenUsername, "s3cret")
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	setRemoteURL(t, repo, test.ServeRepoOverHTTP(t, remotePath, defaultTokenUsername, "s3cret"))
+	t.Setenv(stashconfig.TokenEnvVar, "s3cret")
+	stashconfig.UseCredentialHelper = false
+
+	// Act
+	err = UpdateRepository()
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no credentials used")
+}
+
+func TestHTTPAuth_TokenEnvAndUsernameFromConfig(t *testing.T) {
+	// Arrange
+	_, _, cleanup := setupHTTPRemote(t, "alice", "from-config")
+	defer cleanup()
+	t.Setenv("MY_GIT_TOKEN", "from-config")
+	stashconfig.AuthTokenEnv = "MY_GIT_TOKEN"
+	stashconfig.AuthUsername = "alice"
+	stashconfig.UseCredentialHelper = false
+
+	// Act
+	err := UpdateRepository()
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestHTTPAuth_CredentialHelper(t *testing.T) {
+	// Arrange
+	localPath, _, cleanup := setupHTTPRemote(t, "alice", "helper-secret")
+	defer cleanup()
+	helper := "!f() { echo username=alice; echo password=helper-secret; }; f"
+	out, err := exec.Command("git", "-C", localPath, "config", "credential.helper", helper).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// Act
+	err = UpdateRepository()
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestHTTPAuth_NoCredentials_Fails(t *testing.T) {
+	// Arrange
+	_, _, cleanup := setupHTTPRemote(t, "alice", "s3cret")
+	defer cleanup()
+	stashconfig.UseCredentialHelper = false
+
+	// Act
+	err := UpdateRepository()
+
+	// Assert
+	assert.Error(t, err)
+}
@@ -1,18 +1,49 @@
 package gitx
 
 import (
+	stashconfig "8stash/internal/config"
 	"8stash/internal/validation"
 	"errors"
 	"fmt"
 
+	"github.com/go-git/go-billy/v6/osfs"
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage/filesystem"
 )
 
 const branchNameMustNotEmptyErrorMsg = "branch name must not be empty"
 
-func getRepoContext() (*git.Repository, *git.Worktree, string, string, error) {
+// openRepository opens the repository containing the working directory. PlainOpen keeps
+// .git on a bound filesystem whose temporary files end up outside of it, so go-git cannot
+// rewrite packed-refs, e.g. to delete a ref packed by git clone. The repository is
+// reopened on a chroot filesystem, which keeps temporary files inside .git.
+func openRepository() (*git.Repository, error) {
 	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+	st, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return repo, nil
+	}
+	dotGit, ok := st.Filesystem().(*osfs.BoundOS)
+	if !ok {
+		// e.g. a linked worktree, whose .git is split over two directories
+		return repo, nil
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return repo, nil
+	}
+	storage := filesystem.NewStorage(osfs.New(dotGit.Root()), cache.NewObjectLRUDefault())
+	return git.Open(storage, osfs.New(wt.Filesystem.Root()))
+}
+
+func getRepoContext() (*git.Repository, *git.Worktree, string, string, error) {
+	repo, err := openRepository()
 	if err != nil {
 		return nil, nil, "", "", fmt.Errorf("open repo: %w", err)
 	}
@@ -26,19 +57,36 @@ func getRepoContext() (*git.Repository, *git.Worktree, string, string, error) {
 	if err != nil {
 		return nil, nil, "", "", fmt.Errorf("HEAD: %w", err)
 	}
-	if !head.Name().IsBranch() {
-		return nil, nil, "", "", fmt.Errorf("detached HEAD: cannot operate on current branch")
+	// The branch stays empty on a detached HEAD.
+	var branch string
+	if head.Name().IsBranch() {
+		branch = head.Name().Short()
+	}
+
+	remote := firstNonEmpty(stashconfig.Remote, trackingRemote(repo, branch))
+
+	return repo, wt, branch, remote, nil
+}
+
+// upstreamRef returns the remote-tracking reference of the branch's upstream.
+func upstreamRef(repo *git.Repository, branch string) plumbing.ReferenceName {
+	name := branch
+	if cfg, _ := repo.Config(); cfg != nil {
+		if b, ok := cfg.Branches[branch]; ok && b.Merge != "" {
+			name = b.Merge.Short()
+		}
 	}
-	branch := head.Name().Short()
+	return plumbing.NewRemoteReferenceName(trackingRemote(repo, branch), name)
+}
 
-	remote := "origin"
+// trackingRemote returns the remote the branch tracks, falling back to origin.
+func trackingRemote(repo *git.Repository, branch string) string {
 	if cfg, _ := repo.Config(); cfg != nil {
 		if b, ok := cfg.Branches[branch]; ok && b.Remote != "" {
-			remote = b.Remote
+			return b.Remote
 		}
 	}
-
-	return repo, wt, branch, remote, nil
+	return "origin"
 }
 
 func PrepareRepository() error {
@@ -57,24 +105,83 @@ func PrepareRepository() error {
 	return nil
 }
 
+// PrepareRepositoryWithCommits prepares a push that takes local commits along. The
+// branch may be ahead of or diverged from its upstream, so it is fetched, not pulled.
+func PrepareRepositoryWithCommits() error {
+	if err := validation.IsGitRepository(); err != nil {
+		return err
+	}
+	repo, _, branch, remote, err := getRepoContext()
+	if err != nil {
+		return err
+	}
+	if branch != "" {
+		upstream := trackingRemote(repo, branch)
+		auth, err := remoteAuth(repo, upstream)
+		if err != nil {
+			return err
+		}
+		err = repo.Fetch(&git.FetchOptions{RemoteName: upstream, Auth: auth})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return fmt.Errorf("fetch failed: %w", withAuthMethod(err, auth))
+		}
+	}
+	return fetchStashRefs(repo, remote, stashconfig.BranchPrefix)
+}
+
 func UpdateRepository() error {
-	_, wt, branch, remote, err := getRepoContext()
+	repo, wt, branch, remote, err := getRepoContext()
 	if err != nil {
 		return err
 	}
 
+	if branch == "" {
+		// A detached HEAD has nothing to pull; only the stashes are refreshed.
+		return fetchStashRefs(repo, remote, stashconfig.BranchPrefix)
+	}
+	// The branch is pulled from its own upstream, which may differ from the stash remote.
+	upstream := trackingRemote(repo, branch)
+	auth, err := remoteAuth(repo, upstream)
+	if err != nil {
+		return err
+	}
 	err = wt.Pull(&git.PullOptions{
-		RemoteName:    remote,
+		RemoteName:    upstream,
 		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		Auth:          auth,
 	})
 	switch {
-	case err == nil:
-		return nil
-	case errors.Is(err, git.NoErrAlreadyUpToDate):
-		return nil
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+		// A pull neither prunes stashes dropped by others nor fetches the ref namespace.
+		return fetchStashRefs(repo, remote, stashconfig.BranchPrefix)
 	case errors.Is(err, git.ErrNonFastForwardUpdate):
-		return fmt.Errorf("non fast-forward: local branch diverged from %s/%s", remote, branch)
+		return fmt.Errorf("non fast-forward: local branch diverged from %s/%s", upstream, branch)
 	default:
-		return fmt.Errorf("pull failed: %w", err)
+		return fmt.Errorf("pull failed: %w", withAuthMethod(err, auth))
+	}
+}
+
+// CurrentBranch returns the checked-out branch, or "" on a detached HEAD.
+func CurrentBranch() (string, error) {
+	_, _, branch, _, err := getRepoContext()
+	return branch, err
+}
+
+// CurrentUser returns the git user name and email that push records as the stash author.
+func CurrentUser() (string, string, error) {
+	repo, _, _, _, err := getRepoContext()
+	if err != nil {
+		return "", "", err
+	}
+	return gitUser(repo)
+}
+
+// gitUser reads the identity that stash commits are authored with, so stashes can be
+// matched back to the user who pushed them.
+func gitUser(repo *git.Repository) (string, string, error) {
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", "", fmt.Errorf("read git config: %w", err)
 	}
+	return cfg.User.Name, cfg.User.Email, nil
 }
@@ -13,6 +13,8 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	stashconfig "8stash/internal/config"
 )
 
 func TestUpdateRepository_UpToDate(t *testing.T) {
@@ -230,3 +232,82 @@ func TestPrepareRepository_WithChanges_Succeeds(t *testing.T) {
 	// Assert
 	require.NoError(t, err)
 }
+
+func TestRemoteOption_PushListMergeAndDropUseConfiguredRemote(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	teamPath := t.TempDir()
+	_, err = git.PlainInit(teamPath, true)
+	require.NoError(t, err)
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "team", URLs: []string{teamPath}})
+	require.NoError(t, err)
+	origRemote := stashconfig.Remote
+	stashconfig.Remote = "team"
+	t.Cleanup(func() { stashconfig.Remote = origRemote })
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+	stashName := "8stash/1234"
+
+	// Act
+	err = StashChangesToNewBranch(stashName, StashOptions{})
+	require.NoError(t, err)
+	require.NoError(t, UpdateRepository())
+	stashes, err := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "1234", stashes[0].ID)
+	_, err = repo.Reference(plumbing.NewRemoteReferenceName("origin", stashName), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound, "nothing should be pushed to origin")
+
+	// Act
+	require.NoError(t, repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(stashName)))
+	err = MergeStashIntoCurrentBranch(stashName)
+
+	// Assert
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(localPath, "wip.txt"))
+	assert.NoError(t, err, "stash from the team remote should be applied")
+
+	// Act
+	err = DeleteBranch(stashName)
+
+	// Assert
+	require.NoError(t, err)
+	team, err := git.PlainOpen(teamPath)
+	require.NoError(t, err)
+	_, err = team.Reference(plumbing.NewBranchReferenceName(stashName), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+}
+
+func TestCurrentUser_MatchesStashAuthor(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Alice"
+	cfg.User.Email = "alice@example.com"
+	require.NoError(t, repo.SetConfig(cfg))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+	require.NoError(t, StashChangesToNewBranch("8stash/1", StashOptions{}))
+
+	// Act
+	name, email, err := CurrentUser()
+
+	// Assert
+	require.NoError(t, err)
+	stashes, err := ListStashes("8stash/")
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "Alice", name)
+	assert.Equal(t, "alice@example.com", email)
+	assert.Equal(t, name, stashes[0].AuthorName)
+	assert.Equal(t, email, stashes[0].AuthorEmail)
+}
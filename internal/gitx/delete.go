@@ -36,16 +36,20 @@ func deleteLocal(branchName string, repo *git.Repository, localRefName plumbing.
 func deleteRemote(branchName string, repo *git.Repository, remoteRefSpec config.RefSpec, remoteName string) error {
 	fmt.Printf("trying to delete branch %s on remote\n", branchName)
 
+	auth, err := remoteAuth(repo, remoteName)
+	if err != nil {
+		return err
+	}
 	pushOptions := &git.PushOptions{
 		RemoteName: remoteName,
 		RefSpecs:   []config.RefSpec{remoteRefSpec},
+		Auth:       auth,
 	}
 
 	fmt.Printf("Attempting to delete remote branch '%s' on '%s'...\n", branchName, remoteName)
-	var err error = nil
 	err = repo.Push(pushOptions)
 	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return fmt.Errorf("failed to delete remote branch: %w\n", err)
+		return fmt.Errorf("failed to delete remote branch: %w\n", withAuthMethod(err, auth))
 	}
 
 	fmt.Printf("Remote branch '%s' on '%s' deleted successfully or was not present.\n", branchName, remoteName)
@@ -69,10 +73,18 @@ func DeleteBranch(branchName string) error {
 	}
 
 	// Delete the remote branch
-	remoteRefSpec := config.RefSpec(":" + localRefName.String())
+	remoteRefSpec := config.RefSpec(":" + stashRemoteRef(branchName).String())
 	if err := deleteRemote(branchName, repo, remoteRefSpec, remoteName); err != nil {
 		return err
 	}
 
+	// Stash refs have no remote-tracking branch that a push would clean up.
+	if useRefStorage() {
+		err := repo.Storer.RemoveReference(stashTrackingRef(remoteName, branchName))
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return fmt.Errorf("failed to remove tracking ref of %q: %w", branchName, err)
+		}
+	}
+
 	return nil
 }
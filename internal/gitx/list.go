@@ -2,80 +2,180 @@ package gitx
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+
+	"8stash/internal/naming"
 )
 
-func GetBranchInformationMapsByPrefix(prefix string) (map[string]string, map[string]string, map[string]string, error) {
-	repo, _, _, _, err := getRepoContext()
+// baseBranchHeader is the commit header in which push records the branch a stash was taken from.
+const baseBranchHeader = "8stash-base"
+
+// upstreamHeader marks a stash pushed with its unpushed local commits. It holds the
+// upstream commit those commits start from, or nothing when the branch had no upstream.
+const upstreamHeader = "8stash-upstream"
+
+// Stash describes a stash branch on the remote.
+type Stash struct {
+	ID          string
+	Branch      string
+	Hash        plumbing.Hash
+	Time        time.Time
+	AuthorName  string
+	AuthorEmail string
+	Message     string
+	BaseBranch  string
+	BaseCommit  plumbing.Hash
+	// WithCommits is set when the stash carries local commits below the WIP commit.
+	// BaseCommit is then the upstream commit they start from, if known.
+	WithCommits bool
+}
+
+// ListStashes returns every stash branch on the stash remote starting with prefix,
+// sorted by id with numeric ids in numeric order.
+func ListStashes(prefix string) ([]Stash, error) {
+	repo, _, _, remote, err := getRepoContext()
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	refs, err := repo.References()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get references: %w", err)
+		return nil, fmt.Errorf("failed to get references: %w", err)
 	}
 	defer refs.Close()
 
-	branchToTimeMap := make(map[string]string)
-	branchToAuthorMap := make(map[string]string)
-	branchToMessageMap := make(map[string]string)
-	now := time.Now()
-
+	var stashes []Stash
 	err = refs.ForEach(func(ref *plumbing.Reference) error {
-		return processReference(ref, repo, prefix, now, branchToTimeMap, branchToAuthorMap, branchToMessageMap)
+		branchName, ok := trackedStashName(ref, remote)
+		if !ok || !naming.IsStashBranch(branchName, prefix) {
+			return nil
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get commit for branch %s: %w", branchName, err)
+		}
+		stashes = append(stashes, newStash(branchName, prefix, commit))
+		return nil
 	})
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error processing references: %w", err)
+		return nil, fmt.Errorf("error processing references: %w", err)
 	}
 
-	return branchToTimeMap, branchToAuthorMap, branchToMessageMap, nil
+	sort.Slice(stashes, func(i, j int) bool {
+		return stashLess(stashes[i], stashes[j])
+	})
+	return stashes, nil
 }
 
-func processReference(ref *plumbing.Reference, repo *git.Repository, prefix string, now time.Time, brancheToTimeMap map[string]string, branchToAuthorMap map[string]string, branchToMessageMap map[string]string) error {
-	if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference {
-		return nil
+// stashLess orders numeric ids by value, so 8stash/9 comes before 8stash/10, and
+// puts them before all other ids, which are ordered by branch name.
+func stashLess(a, b Stash) bool {
+	na, errA := strconv.Atoi(a.ID)
+	nb, errB := strconv.Atoi(b.ID)
+	switch {
+	case errA == nil && errB == nil && na != nb:
+		return na < nb
+	case (errA == nil) != (errB == nil):
+		return errA == nil
+	default:
+		return a.Branch < b.Branch
 	}
-	short := ref.Name().Short()
-	parts := strings.SplitN(short, "/", 2)
-	if len(parts) != 2 {
-		return nil
+}
+
+// StashNamesInUse returns the branch names below prefix that a new stash must not
+// take: stashes on the remote, local branches and stashes pending a push.
+func StashNamesInUse(prefix string) ([]string, error) {
+	repo, _, _, remote, err := getRepoContext()
+	if err != nil {
+		return nil, err
 	}
-	remoteName, branchName := parts[0], parts[1]
-	if remoteName != "origin" {
-		return nil
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
 	}
+	defer refs.Close()
 
-	if prefix == "" || strings.HasPrefix(branchName, prefix) {
-		commit, err := repo.CommitObject(ref.Hash())
-		if err != nil {
-			return fmt.Errorf("failed to get commit for branch %s: %w", branchName, err)
+	seen := make(map[string]bool)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name, ok := trackedStashName(ref, remote)
+		if !ok && ref.Name().IsBranch() {
+			name, ok = ref.Name().Short(), true
+		}
+		if !ok {
+			name, ok = strings.CutPrefix(ref.Name().String(), pendingRefPrefix)
+		}
+		if ok && naming.IsStashBranch(name, prefix) {
+			seen[name] = true
 		}
-		timeSince := now.Sub(commit.Author.When)
-		brancheToTimeMap[branchName] = calculateTimeString(timeSince)
-		branchToAuthorMap[branchName] = commit.Author.Name
-		branchToMessageMap[branchName] = commit.Message
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error processing references: %w", err)
+	}
 
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
-	return nil
+	sort.Strings(names)
+	return names, nil
 }
 
-func calculateTimeString(timeSince time.Duration) string {
-	var timeStr string
-	days := int(timeSince.Hours() / 24)
-	if days > 0 {
-		timeStr = fmt.Sprintf("%d days ago", days)
-	} else {
-		hours := int(timeSince.Hours())
-		if hours > 0 {
-			timeStr = fmt.Sprintf("%dh ago", hours)
-		} else {
-			minutes := int(timeSince.Minutes())
-			timeStr = fmt.Sprintf("%dmin ago", minutes)
+func newStash(branchName, prefix string, commit *object.Commit) Stash {
+	s := Stash{
+		ID:          naming.StashID(branchName, prefix),
+		Branch:      branchName,
+		Hash:        commit.Hash,
+		Time:        commit.Author.When,
+		AuthorName:  commit.Author.Name,
+		AuthorEmail: commit.Author.Email,
+		Message:     strings.TrimSpace(commit.Message),
+	}
+	if len(commit.ParentHashes) > 0 {
+		s.BaseCommit = commit.ParentHashes[0]
+	}
+	for _, h := range commit.ExtraHeaders {
+		if h.Key == baseBranchHeader {
+			s.BaseBranch = strings.TrimSpace(h.Value)
 		}
 	}
-	return timeStr
+	if upstream, ok := stashUpstream(commit); ok {
+		s.WithCommits = true
+		if !upstream.IsZero() {
+			s.BaseCommit = upstream
+		}
+	}
+	return s
+}
+
+// stashUpstream reports whether the stash commit carries local commits and returns
+// the upstream commit they start from.
+func stashUpstream(commit *object.Commit) (plumbing.Hash, bool) {
+	for _, h := range commit.ExtraHeaders {
+		if h.Key == upstreamHeader {
+			hash, ok := plumbing.FromHex(strings.TrimSpace(h.Value))
+			if !ok {
+				return plumbing.ZeroHash, true
+			}
+			return hash, true
+		}
+	}
+	return plumbing.ZeroHash, false
+}
+
+// remoteBranchName returns the branch name of a remote-tracking reference of remote.
+func remoteBranchName(ref *plumbing.Reference, remote string) (string, bool) {
+	if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference {
+		return "", false
+	}
+	parts := strings.SplitN(ref.Name().Short(), "/", 2)
+	if len(parts) != 2 || parts[0] != remote {
+		return "", false
+	}
+	return parts[1], true
 }
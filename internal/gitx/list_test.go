@@ -17,7 +17,7 @@ import (
 	"8stash/internal/test"
 )
 
-func TestGetBranchesWithStringName_FilterAndFormatting(t *testing.T) {
+func TestListStashes_FiltersByPrefix(t *testing.T) {
 	// Arrange
 	localPath, cleanup := test.SetupTestRepo(t)
 	defer cleanup()
@@ -95,34 +95,31 @@ func TestGetBranchesWithStringName_FilterAndFormatting(t *testing.T) {
 	}
 
 	// Act
-	allTimes, allAuthors, allMessages, err := GetBranchInformationMapsByPrefix("")
-	require.NoError(t, err)
-	only8stashTimes, only8stashAuthors, only8stashMessages, err := GetBranchInformationMapsByPrefix("8stash/")
-	require.NoError(t, err)
-
-	// Assert - Time map
-	assert.Contains(t, allTimes, "main")
-	assert.Contains(t, allTimes, "8stash/xyz")
-	assert.Contains(t, allTimes, "feature/abc")
-	assert.Contains(t, allTimes, "bugfix/one")
-	assert.NotEmpty(t, allTimes["main"])
-	assert.Len(t, only8stashTimes, 1)
-	assert.Equal(t, "2 days ago", only8stashTimes["8stash/xyz"])
-
-	// Assert - Author map
-	assert.Contains(t, allAuthors, "8stash/xyz", "Author map should contain the stash branch")
-	assert.Len(t, only8stashAuthors, 1)
-	assert.Equal(t, "T", only8stashAuthors["8stash/xyz"], "Author should be 'T' as set in the commit")
-
-	// Assert - Message map
-	assert.Contains(t, allMessages, "8stash/xyz", "Message map should contain the stash branch")
-	assert.Contains(t, allMessages, "feature/abc", "Message map should contain the feature branch")
-	assert.Contains(t, allMessages, "bugfix/one", "Message map should contain the bugfix branch")
-	assert.Len(t, only8stashMessages, 1)
-	assert.Equal(t, "feat xyz", only8stashMessages["8stash/xyz"], "Message should be 'feat xyz' as set in the commit")
+	all, err := ListStashes("")
+	require.NoError(t, err)
+	only8stash, err := ListStashes("8stash/")
+	require.NoError(t, err)
+
+	// Assert
+	var allBranches []string
+	for _, s := range all {
+		allBranches = append(allBranches, s.Branch)
+	}
+	assert.Contains(t, allBranches, "main")
+	assert.Contains(t, allBranches, "8stash/xyz")
+	assert.Contains(t, allBranches, "feature/abc")
+	assert.Contains(t, allBranches, "bugfix/one")
+
+	require.Len(t, only8stash, 1)
+	stash := only8stash[0]
+	assert.Equal(t, "xyz", stash.ID)
+	assert.Equal(t, "8stash/xyz", stash.Branch)
+	assert.WithinDuration(t, twoDaysAgo, stash.Time, time.Second)
+	assert.Equal(t, "T", stash.AuthorName, "Author should be 'T' as set in the commit")
+	assert.Equal(t, "feat xyz", stash.Message, "Message should be 'feat xyz' as set in the commit")
 }
 
-func TestGetBranchesWithStringName_NotARepo(t *testing.T) {
+func TestListStashes_NotARepo(t *testing.T) {
 	// Arrange
 	orig, err := os.Getwd()
 	require.NoError(t, err)
@@ -135,8 +132,93 @@ func TestGetBranchesWithStringName_NotARepo(t *testing.T) {
 	require.NoError(t, os.Chdir(tmp))
 
 	// Act
-	_, _, _, err = GetBranchInformationMapsByPrefix("")
+	_, err = ListStashes("")
 
 	// Assert
 	require.Error(t, err)
 }
+
+func TestListStashes_ReturnsDetailsIncludingBaseBranch(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	headBefore, err := repo.Head()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+	require.NoError(t, StashChangesToNewBranch("8stash/42", StashOptions{Message: "my message"}))
+	test.FetchAll(t, repo)
+
+	// Act
+	stashes, err := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	s := stashes[0]
+	assert.Equal(t, "42", s.ID)
+	assert.Equal(t, "8stash/42", s.Branch)
+	assert.Equal(t, "my message", s.Message)
+	assert.Equal(t, "main", s.BaseBranch)
+	assert.Equal(t, headBefore.Hash(), s.BaseCommit)
+	assert.False(t, s.Hash.IsZero())
+	assert.WithinDuration(t, time.Now(), s.Time, time.Minute)
+	assert.NotEmpty(t, s.AuthorName)
+	assert.NotEmpty(t, s.AuthorEmail)
+}
+
+func TestListStashes_SortsByBranchAndIgnoresOtherPrefixes(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/b", "b.txt", "b", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/a", "a.txt", "a", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "feature/x", "x.txt", "x", time.Now())
+	test.FetchAll(t, repo)
+
+	// Act
+	stashes, err := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, stashes, 2)
+	assert.Equal(t, "8stash/a", stashes[0].Branch)
+	assert.Equal(t, "8stash/b", stashes[1].Branch)
+	assert.Empty(t, stashes[0].BaseBranch) // not pushed by 8stash, so no base branch recorded
+}
+
+func TestListStashes_SortsNumericIDsNumerically(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	for _, id := range []string{"10", "brave-otter", "9", "100"} {
+		test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/"+id, id+".txt", id, time.Now())
+	}
+	test.FetchAll(t, repo)
+
+	// Act
+	stashes, err := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range stashes {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{"9", "10", "100", "brave-otter"}, ids)
+}
@@ -3,18 +3,21 @@ package gitx
 import (
 	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
 )
 
 var ErrNonFastForward = errors.New("non fast-forward merge required")
 
 func findBestRemoteCandidate(candidates []*plumbing.Reference, remote, branchName string) *plumbing.Reference {
 	var fallback *plumbing.Reference
-	prefer := plumbing.ReferenceName("refs/remotes/" + remote + "/" + strings.TrimPrefix(branchName, remote+"/"))
+	prefer := stashTrackingRef(remote, strings.TrimPrefix(branchName, remote+"/"))
 
 	for _, r := range candidates {
 		if r.Name() == prefer {
@@ -27,14 +30,31 @@ func findBestRemoteCandidate(candidates []*plumbing.Reference, remote, branchNam
 	return fallback
 }
 
+// MergeStashIntoCurrentBranch applies a stash as unstaged changes on top of HEAD.
+// Local commits carried by the stash are squashed into those changes.
 func MergeStashIntoCurrentBranch(branchName string) error {
-	repo, wt, currentBranch, _, err := getRepoContext()
+	return mergeStash(branchName, false)
+}
+
+// ReplayStashIntoCurrentBranch brings the local commits carried by the stash onto the
+// current branch, so only the final WIP commit ends up as unstaged changes. The branch
+// is fast-forwarded when it has no commits of its own; otherwise every carried commit
+// is cherry-picked onto HEAD.
+func ReplayStashIntoCurrentBranch(branchName string) error {
+	return mergeStash(branchName, true)
+}
+
+func mergeStash(branchName string, replayCommits bool) error {
+	repo, wt, currentBranch, remote, err := getRepoContext()
 	if err != nil {
 		return err
 	}
 
-	candidates, _ := findRemoteCandidates(repo, branchName)
-	target := findBestRemoteCandidate(candidates, "origin", branchName)
+	candidates, _ := findRemoteCandidates(repo, remote, branchName)
+	target := findBestRemoteCandidate(candidates, remote, branchName)
+	if target == nil {
+		return fmt.Errorf("no suitable remote branch candidate for %q", branchName)
+	}
 
 	headRef, err := repo.Head()
 	if err != nil {
@@ -46,28 +66,49 @@ func MergeStashIntoCurrentBranch(branchName string) error {
 		return err
 	}
 	if !ok {
+		if replayCommits {
+			return replayOntoDivergedHead(repo, wt, target)
+		}
 		return ErrNonFastForward
 	}
 
-	brName := plumbing.NewBranchReferenceName(currentBranch)
-	if err := repo.Storer.SetReference(plumbing.NewHashReference(brName, target.Hash())); err != nil {
+	// HEAD ends up here; everything between it and the stash commit becomes unstaged.
+	keep := headRef.Hash()
+	if replayCommits {
+		commit, err := repo.CommitObject(target.Hash())
+		if err != nil {
+			return fmt.Errorf("read stash commit: %w", err)
+		}
+		if _, ok := stashUpstream(commit); ok && len(commit.ParentHashes) > 0 {
+			keep = commit.ParentHashes[0]
+		}
+	}
+
+	// On a detached HEAD, HEAD itself is moved instead of a branch.
+	refName := plumbing.HEAD
+	if currentBranch != "" {
+		refName = plumbing.NewBranchReferenceName(currentBranch)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, target.Hash())); err != nil {
 		return fmt.Errorf("update branch ref: %w", err)
 	}
 	if err := wt.Reset(&git.ResetOptions{Mode: git.HardReset, Commit: target.Hash()}); err != nil {
 		return fmt.Errorf("reset worktree: %w", err)
 	}
-	return wt.Reset(&git.ResetOptions{Mode: git.MixedReset, Commit: headRef.Hash()})
+	return wt.Reset(&git.ResetOptions{Mode: git.MixedReset, Commit: keep})
 }
 
-// ApplyDivergedMerge: I haven't found a way to do that with Go Git, so I used exec. Maybe you should look into Git Go again.
-// Maybe try looking into Git Go again.
+// ApplyDivergedMerge applies a stash onto a current branch that has diverged from the
+// stash base. Every path changed by the stash is merged three-way against the merge
+// base and left as unstaged changes without any merge state. Conflicting hunks are
+// written with conflict markers and reported as an error.
 func ApplyDivergedMerge(branchName string) error {
-	repo, _, _, remote, err := getRepoContext()
+	repo, wt, _, remote, err := getRepoContext()
 	if err != nil {
 		return err
 	}
 
-	candidates, err := findRemoteCandidates(repo, branchName)
+	candidates, err := findRemoteCandidates(repo, remote, branchName)
 	if err != nil {
 		return err
 	}
@@ -76,21 +117,311 @@ func ApplyDivergedMerge(branchName string) error {
 		return fmt.Errorf("no suitable remote branch candidate for %q", branchName)
 	}
 
-	fullBranchName := targetRef.Name().Short()
+	headRef, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("HEAD: %w", err)
+	}
+	ours, err := repo.CommitObject(headRef.Hash())
+	if err != nil {
+		return fmt.Errorf("read HEAD commit: %w", err)
+	}
+	theirs, err := repo.CommitObject(targetRef.Hash())
+	if err != nil {
+		return fmt.Errorf("read stash commit: %w", err)
+	}
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return fmt.Errorf("merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("no common ancestor between HEAD and %s", targetRef.Name().Short())
+	}
 
-	fmt.Printf("Attempting merge with: git merge --no-commit --no-ff %s", fullBranchName)
+	fmt.Printf("Attempting three-way merge with %s\n", targetRef.Name().Short())
 
-	cmd := exec.Command("git", "merge", "--no-commit", "--no-ff", fullBranchName)
+	_, conflicts, err := mergeChanges(repo, wt, bases[0], theirs, targetRef.Name().Short())
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("automatic merge failed; fix conflicts in the working tree:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
 
-	output, err := cmd.CombinedOutput()
+// replayOntoDivergedHead cherry-picks the local commits carried by a stash onto HEAD
+// one at a time and then merges the WIP commit on top as unstaged changes. A commit
+// that does not apply cleanly stops the replay with its conflicts left in the working
+// tree; the commits replayed before it stay on the branch.
+func replayOntoDivergedHead(repo *git.Repository, wt *git.Worktree, target *plumbing.Reference) error {
+	stash, err := repo.CommitObject(target.Hash())
+	if err != nil {
+		return fmt.Errorf("read stash commit: %w", err)
+	}
+	if len(stash.ParentHashes) == 0 {
+		return fmt.Errorf("stash commit %s has no parent", stash.Hash)
+	}
+	wip, err := stash.Parent(0)
+	if err != nil {
+		return fmt.Errorf("read stash parent: %w", err)
+	}
+	headRef, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("HEAD: %w", err)
+	}
+	carried, err := carriedCommits(repo, wip, headRef.Hash())
+	if err != nil {
+		return err
+	}
 
+	fmt.Printf("Branches have diverged, replaying %d commit(s) from %s onto HEAD...\n", len(carried), target.Name().Short())
+	for _, c := range carried {
+		if err := cherryPick(repo, wt, c); err != nil {
+			return err
+		}
+	}
+
+	_, conflicts, err := mergeChanges(repo, wt, wip, stash, target.Name().Short())
 	if err != nil {
-		return fmt.Errorf("automatic merge failed; fix conflicts and then commit the result:%s", string(output))
+		return err
 	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("the local commits were replayed, but the uncommitted changes conflict; fix conflicts in the working tree:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return nil
+}
 
+// carriedCommits lists the commits between head's history and tip along the first
+// parent chain, oldest first.
+func carriedCommits(repo *git.Repository, tip *object.Commit, head plumbing.Hash) ([]*object.Commit, error) {
+	var carried []*object.Commit
+	for c := tip; ; {
+		contained, err := isAncestor(repo, c.Hash, head)
+		if err != nil {
+			return nil, err
+		}
+		if contained {
+			break
+		}
+		if len(c.ParentHashes) == 0 {
+			return nil, fmt.Errorf("no common ancestor between HEAD and commit %s", c.Hash)
+		}
+		carried = append(carried, c)
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("read parent of %s: %w", c.Hash, err)
+		}
+		c = parent
+	}
+	for i, j := 0, len(carried)-1; i < j; i, j = i+1, j-1 {
+		carried[i], carried[j] = carried[j], carried[i]
+	}
+	return carried, nil
+}
+
+// cherryPick applies the changes of c against its first parent onto HEAD and commits
+// them with the original author and message. A commit whose changes are already on
+// the branch is skipped.
+func cherryPick(repo *git.Repository, wt *git.Worktree, c *object.Commit) error {
+	parent, err := c.Parent(0)
+	if err != nil {
+		return fmt.Errorf("read parent of %s: %w", c.Hash, err)
+	}
+	label := c.Hash.String()[:7]
+	paths, conflicts, err := mergeChanges(repo, wt, parent, c, label)
+	if err != nil {
+		return err
+	}
+	subject := strings.SplitN(c.Message, "\n", 2)[0]
+	if len(conflicts) > 0 {
+		return fmt.Errorf("could not replay commit %s %q; resolve the conflicts in the working tree and commit them:\n%s", label, subject, strings.Join(conflicts, "\n"))
+	}
+	for _, p := range paths {
+		if _, err := wt.Add(p); err != nil {
+			return fmt.Errorf("stage %s: %w", p, err)
+		}
+	}
+
+	opts := &git.CommitOptions{Author: &c.Author}
+	if name, email, err := gitUser(repo); err == nil && name != "" && email != "" {
+		opts.Committer = &object.Signature{Name: name, Email: email, When: time.Now()}
+	}
+	if _, err := wt.Commit(c.Message, opts); err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			fmt.Printf("Skipping commit %s %q: already on the branch\n", label, subject)
+			return nil
+		}
+		return fmt.Errorf("commit %s: %w", label, err)
+	}
 	return nil
 }
 
+// mergeChanges merges every path changed between base and theirs into the working
+// tree, three-way against HEAD. It returns the merged paths and the conflicts found.
+func mergeChanges(repo *git.Repository, wt *git.Worktree, base, theirs *object.Commit, label string) ([]string, []string, error) {
+	headRef, err := repo.Head()
+	if err != nil {
+		return nil, nil, fmt.Errorf("HEAD: %w", err)
+	}
+	ours, err := repo.CommitObject(headRef.Hash())
+	if err != nil {
+		return nil, nil, fmt.Errorf("read HEAD commit: %w", err)
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	oursTree, err := ours.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	theirsTree, err := theirs.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := object.DiffTree(baseTree, theirsTree)
+	if err != nil {
+		return nil, nil, fmt.Errorf("diff stash: %w", err)
+	}
+
+	paths := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		paths = append(paths, changePath(ch))
+	}
+	if err := ensureUntouched(wt, paths); err != nil {
+		return nil, nil, err
+	}
+
+	var conflicts []string
+	for _, p := range paths {
+		conflict, err := mergePath(repo, wt, p, baseTree, oursTree, theirsTree, label)
+		if err != nil {
+			return nil, nil, err
+		}
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return paths, conflicts, nil
+}
+
+func changePath(ch *object.Change) string {
+	if ch.To.Name != "" {
+		return ch.To.Name
+	}
+	return ch.From.Name
+}
+
+// ensureUntouched refuses to merge into paths that carry local changes, so a pop
+// never overwrites uncommitted work.
+func ensureUntouched(wt *git.Worktree, paths []string) error {
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if s, ok := status[p]; ok && (s.Worktree != git.Unmodified || s.Staging != git.Unmodified) {
+			return fmt.Errorf("local changes to %s would be overwritten; commit or stash them first", p)
+		}
+	}
+	return nil
+}
+
+// mergePath merges a single path into the working tree and returns a conflict
+// description when it could not be merged cleanly.
+func mergePath(repo *git.Repository, wt *git.Worktree, path string, baseTree, oursTree, theirsTree *object.Tree, theirsLabel string) (string, error) {
+	baseEntry := findEntry(baseTree, path)
+	oursEntry := findEntry(oursTree, path)
+	theirsEntry := findEntry(theirsTree, path)
+
+	switch {
+	case sameEntry(oursEntry, theirsEntry), sameEntry(theirsEntry, baseEntry):
+		return "", nil
+	case sameEntry(oursEntry, baseEntry):
+		if theirsEntry == nil {
+			if err := wt.Filesystem.Remove(path); err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("remove %s: %w", path, err)
+			}
+			return "", nil
+		}
+		return "", writeBlob(repo, wt, path, theirsEntry.Hash, theirsEntry.Mode)
+	case theirsEntry == nil:
+		return fmt.Sprintf("CONFLICT (modify/delete): %s deleted in stash and modified in HEAD. Version HEAD of %s left in tree.", path, path), nil
+	case oursEntry == nil:
+		if err := writeBlob(repo, wt, path, theirsEntry.Hash, theirsEntry.Mode); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("CONFLICT (modify/delete): %s deleted in HEAD and modified in stash. Version stash of %s left in tree.", path, path), nil
+	}
+
+	base := ""
+	if baseEntry != nil {
+		var err error
+		if base, err = blobContent(repo, baseEntry.Hash); err != nil {
+			return "", err
+		}
+	}
+	ours, err := blobContent(repo, oursEntry.Hash)
+	if err != nil {
+		return "", err
+	}
+	theirs, err := blobContent(repo, theirsEntry.Hash)
+	if err != nil {
+		return "", err
+	}
+	if isBinary(base) || isBinary(ours) || isBinary(theirs) {
+		return fmt.Sprintf("CONFLICT (binary): Cannot merge binary file %s. Version HEAD of %s left in tree.", path, path), nil
+	}
+
+	merged, clean := mergeText(base, ours, theirs, "HEAD", theirsLabel)
+	if err := writeFile(wt, path, []byte(merged), theirsEntry.Mode); err != nil {
+		return "", err
+	}
+	if clean {
+		return "", nil
+	}
+	if baseEntry == nil {
+		return fmt.Sprintf("CONFLICT (add/add): Merge conflict in %s", path), nil
+	}
+	return fmt.Sprintf("CONFLICT (content): Merge conflict in %s", path), nil
+}
+
+func findEntry(tree *object.Tree, path string) *object.TreeEntry {
+	e, err := tree.FindEntry(path)
+	if err != nil {
+		return nil
+	}
+	return e
+}
+
+func sameEntry(a, b *object.TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+func blobContent(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("read blob %s: %w", hash, err)
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return "", fmt.Errorf("read blob %s: %w", hash, err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("read blob %s: %w", hash, err)
+	}
+	return string(b), nil
+}
+
+func isBinary(s string) bool {
+	return strings.IndexByte(s, 0) >= 0
+}
+
 func processCommitNode(repo *git.Repository, hash plumbing.Hash, queue *[]plumbing.Hash, seen map[plumbing.Hash]struct{}) error {
 	commit, err := repo.CommitObject(hash)
 	if err != nil {
@@ -128,9 +459,17 @@ func isAncestor(repo *git.Repository, ancestor, descendant plumbing.Hash) (bool,
 	return false, nil
 }
 
-func findRemoteCandidates(repo *git.Repository, branchName string) ([]*plumbing.Reference, error) {
+func findRemoteCandidates(repo *git.Repository, remote, branchName string) ([]*plumbing.Reference, error) {
 	var out []*plumbing.Reference
 
+	ref, err := repo.Reference(stashTrackingRef(remote, branchName), true)
+	if err == nil {
+		return append(out, ref), nil
+	}
+	if useRefStorage() {
+		return nil, fmt.Errorf("find stash %s on %s: %w", branchName, remote, err)
+	}
+
 	if strings.Contains(branchName, "/") {
 		exact := plumbing.ReferenceName("refs/remotes/" + branchName)
 		if ref, err := repo.Reference(exact, true); err == nil {
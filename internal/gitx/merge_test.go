@@ -252,3 +252,95 @@ func TestApplyDivergedMerge_NoRemoteCandidate_Error(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "no suitable remote branch candidate")
 }
+
+func TestApplyDivergedMerge_WithConflicts_WritesMarkersWithoutMergeState(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	branchName := "8stash/conflict"
+	fileName := "initial.txt"
+
+	// Stash changes the shared file
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branchName),
+		Create: true,
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, fileName), []byte("stash\n"), 0o644))
+	_, err = wt.Add(fileName)
+	require.NoError(t, err)
+	_, err = wt.Commit("stash change", &git.CommitOptions{
+		Author: &object.Signature{Name: "R", Email: "r@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Push(&git.PushOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec("refs/heads/" + branchName + ":refs/heads/" + branchName)},
+	}))
+
+	// Main changes the same file differently
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName("main"),
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, fileName), []byte("main\n"), 0o644))
+	_, err = wt.Add(fileName)
+	require.NoError(t, err)
+	_, err = wt.Commit("main change", &git.CommitOptions{
+		Author: &object.Signature{Name: "L", Email: "l@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	if err := repo.Fetch(&git.FetchOptions{RemoteName: "origin"}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		require.NoError(t, err)
+	}
+
+	// Act
+	err = ApplyDivergedMerge(branchName)
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "CONFLICT (content): Merge conflict in "+fileName)
+
+	b, err := os.ReadFile(filepath.Join(localPath, fileName))
+	require.NoError(t, err)
+	assert.Equal(t, "<<<<<<< HEAD\nmain\n=======\nstash\n>>>>>>> origin/"+branchName+"\n", string(b))
+
+	_, err = os.Stat(filepath.Join(localPath, ".git", "MERGE_HEAD"))
+	assert.True(t, os.IsNotExist(err), "no merge state should be left behind")
+}
+
+func TestApplyDivergedMerge_LocalChangesOnStashedPath_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	branchName := "8stash/dirty"
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, branchName, "initial.txt", "stash", time.Now())
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "other.txt"), []byte("x"), 0o644))
+	_, err = wt.Add("other.txt")
+	require.NoError(t, err)
+	_, err = wt.Commit("diverge", &git.CommitOptions{
+		Author: &object.Signature{Name: "L", Email: "l@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	test.FetchAll(t, repo)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("local work"), 0o644))
+
+	// Act
+	err = ApplyDivergedMerge(branchName)
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "would be overwritten")
+	b, err := os.ReadFile(filepath.Join(localPath, "initial.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "local work", string(b))
+}
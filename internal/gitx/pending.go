@@ -0,0 +1,68 @@
+package gitx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// pendingRefPrefix holds stash commits whose push failed, keyed by stash branch name.
+const pendingRefPrefix = "refs/8stash-pending/"
+
+// ErrStashPending marks a push failure after which the stash commit was kept locally.
+var ErrStashPending = errors.New("stash kept locally as pending")
+
+func savePendingStash(repo *git.Repository, branchName string, hash plumbing.Hash) error {
+	ref := plumbing.NewHashReference(plumbing.ReferenceName(pendingRefPrefix+branchName), hash)
+	return repo.Storer.SetReference(ref)
+}
+
+// PublishPendingStashes pushes every pending stash to its stash branch on the remote
+// and forgets it locally once published. It returns the published branch names.
+func PublishPendingStashes() ([]string, error) {
+	repo, _, _, remote, err := getRepoContext()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	defer refs.Close()
+
+	var pending []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), pendingRefPrefix) {
+			pending = append(pending, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error processing references: %w", err)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Name() < pending[j].Name()
+	})
+
+	var published []string
+	for _, ref := range pending {
+		branchName := strings.TrimPrefix(ref.Name().String(), pendingRefPrefix)
+		refSpec := config.RefSpec(ref.Name().String() + ":" + stashRemoteRef(branchName).String())
+		if err := pushRefSpec(remote, repo, refSpec); err != nil {
+			return published, fmt.Errorf("publish %s: %w", branchName, err)
+		}
+		if err := trackPushedStash(repo, remote, branchName, ref.Hash()); err != nil {
+			return published, err
+		}
+		if err := repo.Storer.RemoveReference(ref.Name()); err != nil {
+			return published, fmt.Errorf("forget pending stash %s: %w", branchName, err)
+		}
+		published = append(published, branchName)
+	}
+	return published, nil
+}
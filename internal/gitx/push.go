@@ -3,48 +3,297 @@ package gitx
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	stashconfig "8stash/internal/config"
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/plumbing/object"
-	"github.com/go-git/go-git/v6/plumbing/transport/ssh"
 )
 
-func StashChangesToNewBranch(newBranchName string, commitMessage string) error {
+// StashOptions selects which working changes StashChangesToNewBranch takes along.
+type StashOptions struct {
+	Message   string
+	Pathspecs []string
+	// StagedOnly stashes only what is in the index and leaves unstaged edits in place.
+	StagedOnly bool
+	// KeepIndex stashes everything but keeps the staged changes in the local tree.
+	KeepIndex bool
+	// Keep publishes the stash but restores the working tree and index exactly afterwards.
+	Keep bool
+	// WithCommits also hands off the local commits that are not on the upstream branch.
+	WithCommits bool
+}
+
+// ErrStashNameTaken marks a push rejected because someone else created a stash
+// with the same name on the remote since it was last fetched.
+var ErrStashNameTaken = errors.New("stash name was taken on the remote in the meantime")
+
+// origHead is where a push returns to: a branch, or a commit when HEAD was detached.
+type origHead struct {
+	branch string
+	hash   plumbing.Hash
+}
+
+func (h origHead) detached() bool {
+	return h.branch == ""
+}
+
+func (h origHead) String() string {
+	if h.detached() {
+		return "detached HEAD " + h.hash.String()[:7]
+	}
+	return h.branch
+}
+
+// checkoutOptions returns the options to check out h with the given flags set.
+func (h origHead) checkoutOptions(force, keep bool) *git.CheckoutOptions {
+	opts := &git.CheckoutOptions{Force: force, Keep: keep}
+	if h.detached() {
+		opts.Hash = h.hash
+	} else {
+		opts.Branch = plumbing.NewBranchReferenceName(h.branch)
+	}
+	return opts
+}
+
+func (h origHead) reference() *plumbing.Reference {
+	if h.detached() {
+		return plumbing.NewHashReference(plumbing.HEAD, h.hash)
+	}
+	return plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(h.branch))
+}
+
+// StashChangesToNewBranch commits the working changes onto newBranchName, pushes it
+// and returns to the original branch, or to the same commit on a detached HEAD.
+// Changes that are not selected by opts stay in the original working tree.
+func StashChangesToNewBranch(newBranchName string, opts StashOptions) error {
+	if opts.StagedOnly && opts.KeepIndex {
+		return errors.New("staged and keep-index cannot be combined")
+	}
 	repo, wt, origBranch, remote, err := getRepoContext()
 	if err != nil {
 		return err
 	}
-	if err := validateBranch(newBranchName, origBranch, repo); err != nil {
+	if err := validateBranch(newBranchName, origBranch, remote, repo); err != nil {
 		return err
 	}
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("HEAD: %w", err)
+	}
+	orig := origHead{branch: origBranch, hash: head.Hash()}
+	var upstream plumbing.Hash
+	if opts.WithCommits {
+		if upstream, err = unpushedBase(repo, orig); err != nil {
+			return err
+		}
+	}
+	withCommits := opts.WithCommits && upstream != orig.hash
 
-	if err := createNewBranchAndSwitch(newBranchName, wt); err != nil {
+	pathspecs, err := normalizePathspecs(wt, opts.Pathspecs)
+	if err != nil {
 		return err
 	}
-	// Stage everything (adds, mods, deletions).
-	if err := stageChanges(wt); err != nil {
+	status, err := wt.Status()
+	if err != nil {
 		return err
 	}
-	// Commit on the new branch.
-	if err := commitChanges(repo, wt, newBranchName, commitMessage); err != nil {
+	matched, rest := partitionChanges(status, pathspecs, opts.StagedOnly)
+	// Local commits alone are worth a stash; the WIP commit on top is then empty.
+	if len(matched) == 0 && !withCommits {
+		switch {
+		case len(pathspecs) > 0:
+			return fmt.Errorf("no changes match pathspec %q", strings.Join(pathspecs, " "))
+		case opts.StagedOnly:
+			return errors.New("no staged changes to stash")
+		case untrackedConfigFile(status):
+			return fmt.Errorf("no changes detected in working tree; the untracked %s is only stashed when named, e.g. 8stash push -- %s", stashconfig.ConfigName, stashconfig.ConfigName)
+		default:
+			return errors.New("no changes detected in working tree")
+		}
+	}
+	// Remember the index so staged changes that are kept survive the round trip.
+	origIdx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("read index: %w", err)
+	}
+	// A second, untouched copy is what a rollback restores.
+	rollbackIdx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("read index: %w", err)
+	}
+
+	if err := createNewBranchAndSwitch(newBranchName, wt); err != nil {
 		return err
 	}
+	headers := stashHeaders(origBranch, upstream, withCommits)
+	stashCommit, err := commitAndPushStash(repo, wt, remote, newBranchName, headers, matched, rest, opts)
+	if err != nil {
+		if !stashCommit.IsZero() && nameTakenOnRemote(repo, remote, newBranchName, stashCommit) {
+			// Retrying under this name can never succeed; let the caller pick another one.
+			return rollbackStash(repo, orig, newBranchName, rollbackIdx, fmt.Errorf("%w: %s", ErrStashNameTaken, newBranchName))
+		}
+		if !stashCommit.IsZero() {
+			// The commit exists but could not be published; keep it for push --retry.
+			if perr := savePendingStash(repo, newBranchName, stashCommit); perr == nil {
+				err = fmt.Errorf("%w: %w", ErrStashPending, err)
+			}
+		}
+		return rollbackStash(repo, orig, newBranchName, rollbackIdx, err)
+	}
+
+	if err := returnToOriginalBranch(repo, wt, orig, status, matched, rest, origIdx, opts); err != nil {
+		err = fmt.Errorf("stash %s was published but switching back to %s failed: %w", newBranchName, orig, err)
+		if !opts.Keep {
+			if rerr := restoreStashedPaths(repo, wt, stashCommit, discardedPaths(status, matched, opts)); rerr != nil {
+				return fmt.Errorf("%w; restoring working tree failed: %w", err, rerr)
+			}
+		}
+		return rollbackStash(repo, orig, newBranchName, rollbackIdx, err)
+	}
+	return trackPushedStash(repo, remote, newBranchName, stashCommit)
+}
+
+// commitAndPushStash commits the selected changes on the stash branch and pushes it.
+// The returned hash is set once the commit exists, even if the push failed.
+func commitAndPushStash(repo *git.Repository, wt *git.Worktree, remote, branchName string, headers []object.ExtraHeader, matched, rest []string, opts StashOptions) (plumbing.Hash, error) {
+	// Keep changes that are not selected out of the stash commit.
+	if len(rest) > 0 {
+		if err := wt.Reset(&git.ResetOptions{Mode: git.MixedReset, Files: rest}); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("unstage unrelated changes: %w", err)
+		}
+	}
+	// Stage the selected changes (adds, mods, deletions); staged-only commits the index as is.
+	if !opts.StagedOnly {
+		if err := stageChanges(wt, matched); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	// Commit on the new branch.
+	if err := commitChanges(repo, wt, branchName, opts.Message, len(matched) == 0, headers); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve stash branch: %w", err)
+	}
 	// Push the new branch to its remote.
-	if err := pushChanges(remote, repo, newBranchName); err != nil {
-		return err
+	return ref.Hash(), pushChanges(remote, repo, branchName)
+}
+
+func returnToOriginalBranch(repo *git.Repository, wt *git.Worktree, orig origHead, status git.Status, matched, rest []string, origIdx *index.Index, opts StashOptions) error {
+	if opts.Keep {
+		// Switch back to the original branch, leaving every local change in place.
+		return switchToBranchKeepingTree(orig, repo, wt, origIdx)
+	}
+	if len(rest) > 0 || opts.StagedOnly || opts.KeepIndex {
+		// Switch back to the original branch, discarding only the stashed changes.
+		return switchToBranchKeepingChanges(orig, repo, wt, status, matched, origIdx, opts)
 	}
 	// Switch back to the original branch, discarding working changes there.
-	if err := switchToBranch(origBranch, wt); err != nil {
-		return err
+	return switchToBranch(orig, wt)
+}
+
+// rollbackStash puts HEAD back where it was, restores the saved index and removes
+// the local stash branch. The working tree is not touched; it still holds the changes.
+func rollbackStash(repo *git.Repository, orig origHead, stashBranch string, origIdx *index.Index, cause error) error {
+	var errs []error
+	if err := repo.Storer.SetReference(orig.reference()); err != nil {
+		errs = append(errs, fmt.Errorf("restore HEAD: %w", err))
+	}
+	if err := repo.Storer.SetIndex(origIdx); err != nil {
+		errs = append(errs, fmt.Errorf("restore index: %w", err))
+	}
+	if err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(stashBranch)); err != nil {
+		errs = append(errs, fmt.Errorf("remove branch %s: %w", stashBranch, err))
 	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w; rollback failed: %w", cause, errors.Join(errs...))
+	}
+	return cause
+}
+
+// discardedPaths returns the stashed paths whose working copy is reverted when switching back.
+func discardedPaths(status git.Status, matched []string, opts StashOptions) []string {
+	var paths []string
+	for _, p := range matched {
+		if opts.StagedOnly && status[p].Worktree != git.Unmodified {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
 
+// restoreStashedPaths writes the stashed content of paths back into the working tree.
+// The stash commit holds exactly what the working tree had for these paths.
+func restoreStashedPaths(repo *git.Repository, wt *git.Worktree, commitHash plumbing.Hash, paths []string) error {
+	commit, err := repo.CommitObject(commitHash)
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		f, err := tree.File(p)
+		if errors.Is(err, object.ErrFileNotFound) {
+			if err := wt.Filesystem.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove %s: %w", p, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeBlob(repo, wt, p, f.Hash, f.Mode); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func validateBranch(branchName string, origBranch string, repo *git.Repository) error {
+// unpushedBase returns the upstream commit the local commits of orig start from,
+// or the zero hash when orig has no upstream branch.
+func unpushedBase(repo *git.Repository, orig origHead) (plumbing.Hash, error) {
+	if orig.detached() {
+		return plumbing.ZeroHash, nil
+	}
+	ref, err := repo.Reference(upstreamRef(repo, orig.branch), true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve upstream of %s: %w", orig.branch, err)
+	}
+	head, err := repo.CommitObject(orig.hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("read HEAD commit: %w", err)
+	}
+	upstream, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("read upstream commit: %w", err)
+	}
+	bases, err := head.MergeBase(upstream)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return bases[0].Hash, nil
+}
+
+func validateBranch(branchName string, origBranch string, remote string, repo *git.Repository) error {
 	if branchName == "" {
 		return fmt.Errorf(branchNameMustNotEmptyErrorMsg)
 	}
@@ -57,16 +306,26 @@ func validateBranch(branchName string, origBranch string, repo *git.Repository)
 	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
 		return err
 	}
+	// Pushing over a stash of someone else would silently replace it.
+	if _, err := repo.Reference(stashTrackingRef(remote, branchName), true); err == nil {
+		return fmt.Errorf("stash %q already exists on %s", branchName, remote)
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
 
 	return nil
 }
 
-func stageChanges(wt *git.Worktree) error {
+func stageChanges(wt *git.Worktree, paths []string) error {
 	status, err := wt.Status()
 	if err != nil {
 		return err
 	}
-	for path, s := range status {
+	for _, path := range paths {
+		s, ok := status[path]
+		if !ok {
+			continue
+		}
 		// Stage deletions.
 		if s.Worktree == git.Deleted || s.Staging == git.Deleted {
 			if _, err := wt.Remove(path); err != nil {
@@ -82,16 +341,126 @@ func stageChanges(wt *git.Worktree) error {
 	return nil
 }
 
-func commitChanges(repo *git.Repository, wt *git.Worktree, branchName string, commitMessage string) error {
-	var authorName string
-	var authorEmail string
-	cfg, err := repo.Config()
+// untrackedConfigFile reports whether the repository config file is untracked.
+func untrackedConfigFile(status git.Status) bool {
+	s, ok := status[stashconfig.ConfigName]
+	return ok && s.Worktree == git.Untracked
+}
+
+// partitionChanges splits the changed paths into those selected for the stash and
+// the remaining ones. Without pathspecs every change matches, with stagedOnly only
+// paths that have staged changes are selected. An untracked .8stash.yaml, e.g. written
+// by config init, is only selected by a pathspec, so stashing does not silently revert
+// the config.
+func partitionChanges(status git.Status, pathspecs []string, stagedOnly bool) ([]string, []string) {
+	var matched, rest []string
+	for path, s := range status {
+		if s.Worktree == git.Unmodified && s.Staging == git.Unmodified {
+			continue
+		}
+		selected := len(pathspecs) == 0 || matchesPathspec(path, pathspecs)
+		if stagedOnly && !isStaged(s) {
+			selected = false
+		}
+		if len(pathspecs) == 0 && path == stashconfig.ConfigName && s.Worktree == git.Untracked {
+			selected = false
+		}
+		if selected {
+			matched = append(matched, path)
+		} else {
+			rest = append(rest, path)
+		}
+	}
+	return matched, rest
+}
+
+func isStaged(s *git.FileStatus) bool {
+	return s.Staging != git.Unmodified && s.Staging != git.Untracked
+}
 
+// normalizePathspecs makes pathspecs relative to the repository root, so they
+// behave like git pathspecs when 8stash runs in a subdirectory.
+func normalizePathspecs(wt *git.Worktree, pathspecs []string) ([]string, error) {
+	if len(pathspecs) == 0 {
+		return nil, nil
+	}
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return nil, fmt.Errorf("resolve repository root: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+	if cwd, err = filepath.EvalSymlinks(cwd); err != nil {
+		return nil, fmt.Errorf("resolve working directory: %w", err)
+	}
+
+	out := make([]string, 0, len(pathspecs))
+	for _, spec := range pathspecs {
+		abs := spec
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(cwd, spec)
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("pathspec %q is outside repository", spec)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out, nil
+}
+
+// matchesPathspec reports whether a slash separated path is selected by one of the
+// pathspecs. Like git, a pathspec matches the path itself, everything below a
+// directory, and glob wildcards may match across directory separators.
+func matchesPathspec(file string, pathspecs []string) bool {
+	for _, spec := range pathspecs {
+		spec = strings.TrimSuffix(spec, "/")
+		if spec == "." || spec == "" {
+			return true
+		}
+		if file == spec || strings.HasPrefix(file, spec+"/") {
+			return true
+		}
+		if re, err := regexp.Compile("^" + globToRegexp(spec) + "(/.*)?$"); err == nil && re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	inClass := false
+	for i, r := range glob {
+		switch {
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			if r == '!' && glob[i-1] == '[' {
+				r = '^'
+			}
+			b.WriteRune(r)
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		case r == '[':
+			inClass = true
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func commitChanges(repo *git.Repository, wt *git.Worktree, branchName string, commitMessage string, allowEmpty bool, headers []object.ExtraHeader) error {
+	authorName, authorEmail, err := gitUser(repo)
 	if err != nil {
 		fmt.Printf("Warning: failed to get git config, using default author: %v\n", err)
-	} else {
-		authorName = cfg.User.Name
-		authorEmail = cfg.User.Email
 	}
 
 	if authorName == "" {
@@ -106,7 +475,7 @@ func commitChanges(repo *git.Repository, wt *git.Worktree, branchName string, co
 		commitMessage = fmt.Sprintf("move local changes to branch %s", branchName)
 	}
 
-	if _, err := wt.Commit(
+	hash, err := wt.Commit(
 		commitMessage,
 		&git.CommitOptions{
 			Author: &object.Signature{
@@ -114,23 +483,96 @@ func commitChanges(repo *git.Repository, wt *git.Worktree, branchName string, co
 				Email: authorEmail,
 				When:  time.Now(),
 			},
+			AllowEmptyCommits: allowEmpty,
 		},
-	); err != nil {
+	)
+	if err != nil {
 		return err
 	}
-	return nil
+	return recordHeaders(repo, branchName, hash, headers)
+}
+
+// stashHeaders returns the commit headers describing where a stash was taken from.
+// Stashes from a detached HEAD get no base branch; their parent commit is the base.
+func stashHeaders(baseBranch string, upstream plumbing.Hash, withCommits bool) []object.ExtraHeader {
+	var headers []object.ExtraHeader
+	if baseBranch != "" {
+		headers = append(headers, object.ExtraHeader{Key: baseBranchHeader, Value: baseBranch})
+	}
+	if withCommits {
+		value := ""
+		if !upstream.IsZero() {
+			value = upstream.String()
+		}
+		headers = append(headers, object.ExtraHeader{Key: upstreamHeader, Value: value})
+	}
+	return headers
+}
+
+// recordHeaders rewrites the stash commit with extra headers, so list can report
+// where the stash came from without touching the message.
+func recordHeaders(repo *git.Repository, branchName string, hash plumbing.Hash, headers []object.ExtraHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("read stash commit: %w", err)
+	}
+	commit.ExtraHeaders = append(commit.ExtraHeaders, headers...)
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return fmt.Errorf("encode stash commit: %w", err)
+	}
+	newHash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("store stash commit: %w", err)
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branchName), newHash))
 }
 
 func pushChanges(remote string, repo *git.Repository, branchName string) error {
+	return pushRefSpec(remote, repo, config.RefSpec("refs/heads/"+branchName+":"+stashRemoteRef(branchName).String()))
+}
+
+// nameTakenOnRemote reports whether the remote holds a stash named name that is not
+// stash, i.e. a failed push lost the race for the name. go-git reports the rejection
+// only as text, so the remote is asked instead.
+func nameTakenOnRemote(repo *git.Repository, remote, name string, stash plumbing.Hash) bool {
+	r, err := repo.Remote(remote)
+	if err != nil {
+		return false
+	}
+	auth, err := remoteAuth(repo, remote)
+	if err != nil {
+		return false
+	}
+	refs, err := r.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return false
+	}
+	target := stashRemoteRef(name)
+	for _, ref := range refs {
+		if ref.Name() == target {
+			return ref.Hash() != stash
+		}
+	}
+	return false
+}
+
+func pushRefSpec(remote string, repo *git.Repository, refSpec config.RefSpec) error {
+	auth, err := remoteAuth(repo, remote)
+	if err != nil {
+		return err
+	}
 	pushOpts := &git.PushOptions{
 		RemoteName: remote,
-		RefSpecs:   []config.RefSpec{config.RefSpec("refs/heads/" + branchName + ":refs/heads/" + branchName)},
-	}
-	if auth, err := ssh.NewSSHAgentAuth("git"); err == nil && auth != nil {
-		pushOpts.Auth = auth
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
 	}
 	if err := repo.Push(pushOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return fmt.Errorf("push failed: %w", err)
+		return fmt.Errorf("push failed: %w", withAuthMethod(err, auth))
 	}
 	return nil
 }
@@ -143,13 +585,129 @@ func createNewBranchAndSwitch(branchName string, wt *git.Worktree) error {
 	})
 }
 
-func switchToBranch(branchName string, wt *git.Worktree) error {
-	if err := wt.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.NewBranchReferenceName(branchName),
-		Force:  true,
-		Keep:   false,
-	}); err != nil {
+func switchToBranch(orig origHead, wt *git.Worktree) error {
+	if err := wt.Checkout(orig.checkoutOptions(true, false)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// switchToBranchKeepingChanges returns to orig and reverts only the stashed
+// paths, leaving all other working tree changes and their staged state untouched.
+// With KeepIndex the staged content of stashed paths is put back afterwards, with
+// StagedOnly files that also carry unstaged edits keep their working copy.
+func switchToBranchKeepingChanges(orig origHead, repo *git.Repository, wt *git.Worktree, status git.Status, stashedPaths []string, origIdx *index.Index, opts StashOptions) error {
+	if err := wt.Checkout(orig.checkoutOptions(false, true)); err != nil {
+		return err
+	}
+
+	var discard, unstage, resetIdx, keepStaged []string
+	for _, p := range stashedPaths {
+		s := status[p]
+		if opts.StagedOnly && s.Worktree != git.Unmodified {
+			unstage = append(unstage, p)
+		} else {
+			discard = append(discard, p)
+		}
+		if opts.KeepIndex && isStaged(s) {
+			keepStaged = append(keepStaged, p)
+		} else {
+			resetIdx = append(resetIdx, p)
+		}
+	}
+	if len(discard) > 0 {
+		if err := wt.Reset(&git.ResetOptions{Mode: git.HardReset, Files: discard}); err != nil {
+			return fmt.Errorf("discard stashed changes: %w", err)
+		}
+	}
+	if len(unstage) > 0 {
+		if err := wt.Reset(&git.ResetOptions{Mode: git.MixedReset, Files: unstage}); err != nil {
+			return fmt.Errorf("unstage stashed changes: %w", err)
+		}
+	}
+	if err := restoreIndex(repo, origIdx, resetIdx); err != nil {
+		return err
+	}
+	return checkoutIndexEntries(repo, wt, origIdx, keepStaged)
+}
+
+// switchToBranchKeepingTree returns to orig without touching the working
+// tree and puts the saved index back, so untracked files become untracked again.
+func switchToBranchKeepingTree(orig origHead, repo *git.Repository, wt *git.Worktree, origIdx *index.Index) error {
+	if err := wt.Checkout(orig.checkoutOptions(false, true)); err != nil {
+		return err
+	}
+	return restoreIndex(repo, origIdx, nil)
+}
+
+// checkoutIndexEntries writes the staged content of the given paths into the
+// working tree. Paths without an index entry are removed.
+func checkoutIndexEntries(repo *git.Repository, wt *git.Worktree, idx *index.Index, paths []string) error {
+	for _, p := range paths {
+		e, err := idx.Entry(p)
+		if errors.Is(err, index.ErrEntryNotFound) {
+			if err := wt.Filesystem.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove %s: %w", p, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeBlob(repo, wt, p, e.Hash, e.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBlob(repo *git.Repository, wt *git.Worktree, path string, hash plumbing.Hash, mode filemode.FileMode) error {
+	content, err := blobContent(repo, hash)
+	if err != nil {
 		return err
 	}
+	return writeFile(wt, path, []byte(content), mode)
+}
+
+func writeFile(wt *git.Worktree, path string, content []byte, mode filemode.FileMode) error {
+	if mode == filemode.Symlink {
+		_ = wt.Filesystem.Remove(path)
+		return wt.Filesystem.Symlink(string(content), path)
+	}
+
+	osMode, err := mode.ToOSFileMode()
+	if err != nil {
+		osMode = 0o644
+	}
+	if err := wt.Filesystem.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", path, err)
+	}
+	f, err := wt.Filesystem.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, osMode)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// restoreIndex writes back a previously saved index, taking the entries for the
+// given paths from the current index instead.
+func restoreIndex(repo *git.Repository, saved *index.Index, currentPaths []string) error {
+	current, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("read index: %w", err)
+	}
+	for _, p := range currentPaths {
+		_, _ = saved.Remove(p)
+		if e, err := current.Entry(p); err == nil {
+			saved.Entries = append(saved.Entries, e)
+		}
+	}
+	if err := repo.Storer.SetIndex(saved); err != nil {
+		return fmt.Errorf("restore index: %w", err)
+	}
 	return nil
 }
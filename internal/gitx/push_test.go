@@ -5,12 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v6"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	stashconfig "8stash/internal/config"
 	"8stash/internal/test"
 )
 
@@ -23,7 +25,7 @@ func TestStashChangesToNewBranch(t *testing.T) {
 	newBranchName := "feature/new-stuff"
 
 	// Act
-	err := StashChangesToNewBranch(newBranchName, "")
+	err := StashChangesToNewBranch(newBranchName, StashOptions{})
 
 	// Assert
 	require.NoError(t, err) // operation succeeds without error
@@ -63,7 +65,7 @@ func TestStashChangesToNewBranch_EmptyName_Error(t *testing.T) {
 	defer cleanup()
 
 	// Act
-	err := StashChangesToNewBranch("", "")
+	err := StashChangesToNewBranch("", StashOptions{})
 
 	// Assert
 	require.Error(t, err)
@@ -76,7 +78,7 @@ func TestStashChangesToNewBranch_TargetEqualsCurrent_Error(t *testing.T) {
 	defer cleanup()
 
 	// Act
-	err := StashChangesToNewBranch("main", "")
+	err := StashChangesToNewBranch("main", StashOptions{})
 
 	// Assert
 	require.Error(t, err)
@@ -104,13 +106,63 @@ func TestStashChangesToNewBranch_TargetAlreadyExists_Error(t *testing.T) {
 	}))
 
 	// Act
-	err = StashChangesToNewBranch(exists, "")
+	err = StashChangesToNewBranch(exists, StashOptions{})
 
 	// Assert
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "already exists")
 }
 
+func TestStashChangesToNewBranch_TargetExistsOnRemote_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	taken := plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", "8stash/42"), head.Hash())
+	require.NoError(t, repo.Storer.SetReference(taken))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	// Act
+	err = StashChangesToNewBranch("8stash/42", StashOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `stash "8stash/42" already exists on origin`)
+	_, err = os.Stat(filepath.Join(localPath, "wip.txt"))
+	assert.NoError(t, err) // changes are untouched
+}
+
+func TestStashChangesToNewBranch_NameTakenOnRemoteSinceFetch_ReturnsErrStashNameTaken(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	// Someone else pushed 8stash/1; this clone has not fetched it yet.
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/1", "theirs.txt", "theirs", time.Now())
+	require.NoError(t, repo.Storer.RemoveReference(plumbing.NewBranchReferenceName("8stash/1")))
+	require.NoError(t, repo.Storer.RemoveReference(plumbing.NewRemoteReferenceName("origin", "8stash/1")))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	// Act
+	err = StashChangesToNewBranch("8stash/1", StashOptions{})
+
+	// Assert
+	require.ErrorIs(t, err, ErrStashNameTaken)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+	_, err = os.Stat(filepath.Join(localPath, "wip.txt"))
+	assert.NoError(t, err) // changes are untouched
+	_, err = repo.Reference(plumbing.ReferenceName(pendingRefPrefix+"8stash/1"), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound) // nothing left for push --retry
+}
+
 func TestStashChangesToNewBranch_WithCustomMessage_UsesCustomMessage(t *testing.T) {
     // Arrange
     localPath, cleanup := test.SetupTestRepo(t)
@@ -121,7 +173,7 @@ func TestStashChangesToNewBranch_WithCustomMessage_UsesCustomMessage(t *testing.
     customMessage := "WIP: implementing new login flow"
 
     // Act
-    err := StashChangesToNewBranch(newBranchName, customMessage)
+    err := StashChangesToNewBranch(newBranchName, StashOptions{Message: customMessage})
 
     // Assert
     require.NoError(t, err)
@@ -143,7 +195,7 @@ func TestStashChangesToNewBranch_WithEmptyMessage_UsesDefaultMessage(t *testing.
     newBranchName := "feature/default-msg"
 
     // Act
-    err := StashChangesToNewBranch(newBranchName, "")
+    err := StashChangesToNewBranch(newBranchName, StashOptions{})
 
     // Assert
     require.NoError(t, err)
@@ -155,4 +207,459 @@ func TestStashChangesToNewBranch_WithEmptyMessage_UsesDefaultMessage(t *testing.
     require.NoError(t, err)
     expectedDefaultMsg := fmt.Sprintf("move local changes to branch %s", newBranchName)
     assert.Equal(t, expectedDefaultMsg, commit.Message)
-}
\ No newline at end of file
+}
+
+func TestStashChangesToNewBranch_WithPathspec_StashesOnlyMatchingChanges(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.MkdirAll(filepath.Join(localPath, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "src", "a.go"), []byte("package a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "notes.txt"), []byte("keep me"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("staged edit"), 0o644))
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("initial.txt")
+	require.NoError(t, err)
+	newBranchName := "feature/pathspec"
+
+	// Act
+	err = StashChangesToNewBranch(newBranchName, StashOptions{Pathspecs: []string{"src"}})
+
+	// Assert
+	require.NoError(t, err)
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+
+	_, err = os.Stat(filepath.Join(localPath, "src", "a.go"))
+	assert.True(t, os.IsNotExist(err)) // stashed file is gone locally
+
+	b, err := os.ReadFile(filepath.Join(localPath, "notes.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(b)) // unrelated untracked file stays
+
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, git.Modified, status.File("initial.txt").Staging) // unrelated staged change stays staged
+	assert.Equal(t, git.Untracked, status.File("notes.txt").Worktree)
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	_, err = commit.File("src/a.go")
+	assert.NoError(t, err)
+	_, err = commit.File("notes.txt")
+	assert.Error(t, err)
+	f, err := commit.File("initial.txt")
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "init", content) // staged change outside the pathspec is not stashed
+}
+
+func TestStashChangesToNewBranch_WithPathspec_NoMatch_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "notes.txt"), []byte("x"), 0o644))
+
+	// Act
+	err := StashChangesToNewBranch("feature/nomatch", StashOptions{Pathspecs: []string{"*.go"}})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no changes match pathspec")
+}
+
+func TestStashChangesToNewBranch_UntrackedConfigFile_StaysInWorktree(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, stashconfig.ConfigName), []byte("remote: upstream\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "notes.txt"), []byte("stash me"), 0o644))
+	newBranchName := "feature/config"
+
+	// Act
+	err := StashChangesToNewBranch(newBranchName, StashOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(localPath, stashconfig.ConfigName))
+	require.NoError(t, err)
+	assert.Equal(t, "remote: upstream\n", string(b))
+	_, err = os.Stat(filepath.Join(localPath, "notes.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	_, err = commit.File(stashconfig.ConfigName)
+	assert.Error(t, err)
+}
+
+func TestStashChangesToNewBranch_OnlyUntrackedConfigFile_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, stashconfig.ConfigName), []byte("remote: upstream\n"), 0o644))
+
+	// Act
+	err := StashChangesToNewBranch("feature/config-only", StashOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no changes detected")
+	assert.ErrorContains(t, err, "only stashed when named")
+}
+
+func TestStashChangesToNewBranch_UntrackedConfigFileNamedByPathspec_IsStashed(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, stashconfig.ConfigName), []byte("remote: upstream\n"), 0o644))
+	newBranchName := "feature/config-named"
+
+	// Act
+	err := StashChangesToNewBranch(newBranchName, StashOptions{Pathspecs: []string{stashconfig.ConfigName}})
+
+	// Assert
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(localPath, stashconfig.ConfigName))
+	assert.True(t, os.IsNotExist(err))
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	_, err = commit.File(stashconfig.ConfigName)
+	assert.NoError(t, err)
+}
+
+func TestMatchesPathspec(t *testing.T) {
+	tests := []struct {
+		file     string
+		specs    []string
+		expected bool
+	}{
+		{"src/a.go", []string{"src"}, true},
+		{"src/a.go", []string{"src/"}, true},
+		{"src/a.go", []string{"*.go"}, true},
+		{"src/sub/a.go", []string{"src/*"}, true},
+		{"srcx/a.go", []string{"src"}, false},
+		{"notes.txt", []string{"*.go"}, false},
+		{"notes.txt", []string{"."}, true},
+		{"notes.txt", []string{"src", "notes.txt"}, true},
+		{"b.txt", []string{"[!a].txt"}, true},
+		{"a.txt", []string{"[!a].txt"}, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, matchesPathspec(tc.file, tc.specs), "file %s specs %v", tc.file, tc.specs)
+	}
+}
+
+func TestStashChangesToNewBranch_StagedOnly_LeavesUnstagedChanges(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "staged.txt"), []byte("staged"), 0o644))
+	_, err = wt.Add("staged.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("unstaged edit"), 0o644))
+	newBranchName := "feature/staged"
+
+	// Act
+	err = StashChangesToNewBranch(newBranchName, StashOptions{StagedOnly: true})
+
+	// Assert
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(localPath, "staged.txt"))
+	assert.True(t, os.IsNotExist(err)) // staged file was stashed
+
+	b, err := os.ReadFile(filepath.Join(localPath, "initial.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "unstaged edit", string(b)) // unstaged edit stays
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	_, err = commit.File("staged.txt")
+	assert.NoError(t, err)
+	f, err := commit.File("initial.txt")
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "init", content) // unstaged edit is not part of the stash
+}
+
+func TestStashChangesToNewBranch_StagedOnly_NothingStaged_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("unstaged edit"), 0o644))
+
+	// Act
+	err := StashChangesToNewBranch("feature/nothing-staged", StashOptions{StagedOnly: true})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no staged changes to stash")
+}
+
+func TestStashChangesToNewBranch_KeepIndex_KeepsStagedChangesLocally(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "staged.txt"), []byte("staged"), 0o644))
+	_, err = wt.Add("staged.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("unstaged edit"), 0o644))
+	newBranchName := "feature/keep-index"
+
+	// Act
+	err = StashChangesToNewBranch(newBranchName, StashOptions{KeepIndex: true})
+
+	// Assert
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filepath.Join(localPath, "staged.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "staged", string(b)) // staged file stays
+
+	b, err = os.ReadFile(filepath.Join(localPath, "initial.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "init", string(b)) // unstaged edit was stashed and reverted
+
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, git.Added, status.File("staged.txt").Staging)
+	if s, ok := status["initial.txt"]; ok {
+		assert.Equal(t, git.Unmodified, s.Worktree)
+	}
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	_, err = commit.File("staged.txt")
+	assert.NoError(t, err)
+	f, err := commit.File("initial.txt")
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "unstaged edit", content) // everything is part of the stash
+}
+
+func TestStashChangesToNewBranch_StagedAndKeepIndex_Error(t *testing.T) {
+	// Arrange
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	// Act
+	err := StashChangesToNewBranch("feature/both", StashOptions{StagedOnly: true, KeepIndex: true})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "cannot be combined")
+}
+
+func TestStashChangesToNewBranch_Keep_RestoresWorkingTreeAndIndex(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "staged.txt"), []byte("staged"), 0o644))
+	_, err = wt.Add("staged.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("unstaged edit"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "untracked.txt"), []byte("untracked"), 0o644))
+	statusBefore, err := wt.Status()
+	require.NoError(t, err)
+	newBranchName := "feature/keep"
+
+	// Act
+	err = StashChangesToNewBranch(newBranchName, StashOptions{Keep: true})
+
+	// Assert
+	require.NoError(t, err)
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+
+	statusAfter, err := wt.Status()
+	require.NoError(t, err)
+	require.Len(t, statusAfter, len(statusBefore))
+	for path, before := range statusBefore {
+		after, ok := statusAfter[path]
+		require.True(t, ok, "missing status for %s", path)
+		assert.Equal(t, *before, *after, "status of %s changed", path) // identical local state
+	}
+
+	b, err := os.ReadFile(filepath.Join(localPath, "initial.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "unstaged edit", string(b))
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(newBranchName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	for _, name := range []string{"staged.txt", "initial.txt", "untracked.txt"} {
+		_, err = commit.File(name)
+		assert.NoError(t, err, "stash should contain %s", name)
+	}
+
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	found := false
+	for _, r := range refs {
+		if r.Name().String() == "refs/heads/"+newBranchName {
+			found = true
+		}
+	}
+	assert.True(t, found) // stash was published
+}
+
+func setRemoteURL(t *testing.T, repo *git.Repository, url string) string {
+	t.Helper()
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	orig := cfg.Remotes["origin"].URLs[0]
+	cfg.Remotes["origin"].URLs = []string{url}
+	require.NoError(t, repo.SetConfig(cfg))
+	return orig
+}
+
+func TestStashChangesToNewBranch_PushFails_RollsBackAndKeepsPendingStash(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "staged.txt"), []byte("staged"), 0o644))
+	_, err = wt.Add("staged.txt")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "initial.txt"), []byte("unstaged edit"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "untracked.txt"), []byte("untracked"), 0o644))
+	statusBefore, err := wt.Status()
+	require.NoError(t, err)
+	remoteURL := setRemoteURL(t, repo, filepath.Join(localPath, "does-not-exist"))
+	newBranchName := "8stash/offline"
+
+	// Act
+	err = StashChangesToNewBranch(newBranchName, StashOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrStashPending)
+	assert.ErrorContains(t, err, "push failed")
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(newBranchName), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound) // temporary branch removed
+
+	statusAfter, err := wt.Status()
+	require.NoError(t, err)
+	require.Len(t, statusAfter, len(statusBefore))
+	for path, before := range statusBefore {
+		after, ok := statusAfter[path]
+		require.True(t, ok, "missing status for %s", path)
+		assert.Equal(t, *before, *after, "status of %s changed", path)
+	}
+	b, err := os.ReadFile(filepath.Join(localPath, "initial.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "unstaged edit", string(b))
+
+	// Act - publish the pending stash once the remote is reachable again
+	setRemoteURL(t, repo, remoteURL)
+	published, err := PublishPendingStashes()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{newBranchName}, published)
+
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	var stash *plumbing.Reference
+	for _, r := range refs {
+		if r.Name().String() == "refs/heads/"+newBranchName {
+			stash = r
+		}
+	}
+	require.NotNil(t, stash, "pending stash should be published")
+	commit, err := repo.CommitObject(stash.Hash())
+	require.NoError(t, err)
+	for _, name := range []string{"staged.txt", "initial.txt", "untracked.txt"} {
+		_, err = commit.File(name)
+		assert.NoError(t, err, "stash should contain %s", name)
+	}
+
+	published, err = PublishPendingStashes()
+	require.NoError(t, err)
+	assert.Empty(t, published) // nothing left to publish
+}
+
+func TestStashChangesToNewBranch_DetachedHead_ReturnsToSameCommit(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Hash: head.Hash()}))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "bisect-notes.txt"), []byte("notes"), 0o644))
+	stashName := "8stash/1234"
+
+	// Act
+	err = StashChangesToNewBranch(stashName, StashOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	after, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.HEAD, after.Name(), "HEAD should still be detached")
+	assert.Equal(t, head.Hash(), after.Hash())
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean())
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", stashName), true)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(remoteRef.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{head.Hash()}, commit.ParentHashes, "the detached commit is the stash base")
+	assert.Empty(t, commit.ExtraHeaders)
+}
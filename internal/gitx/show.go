@@ -0,0 +1,55 @@
+package gitx
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// StashPatch returns the changes a stash commit introduces on top of its parent. For a
+// stash pushed with its local commits that is the upstream commit they start from, so
+// the patch covers the carried commits as well.
+func StashPatch(hash plumbing.Hash) (*object.Patch, error) {
+	repo, _, _, _, err := getRepoContext()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stash commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stash tree: %w", err)
+	}
+
+	// a stash without a parent is diffed against the empty tree
+	parentTree := &object.Tree{}
+	if upstream, ok := stashUpstream(commit); ok {
+		// without an upstream every carried commit is new, so the empty tree stays the base
+		if !upstream.IsZero() {
+			base, err := repo.CommitObject(upstream)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get upstream commit %s of stash: %w", upstream, err)
+			}
+			if parentTree, err = base.Tree(); err != nil {
+				return nil, fmt.Errorf("failed to get upstream tree: %w", err)
+			}
+		}
+	} else if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent of stash commit: %w", err)
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get parent tree: %w", err)
+		}
+	}
+
+	patch, err := parentTree.Patch(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stash diff: %w", err)
+	}
+	return patch, nil
+}
@@ -0,0 +1,185 @@
+package gitx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh/knownhosts"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+
+	stashconfig "8stash/internal/config"
+)
+
+// SSHPassphraseEnvVar holds the passphrase of an encrypted key when no terminal is available.
+const SSHPassphraseEnvVar = "EIGHTSTASH_SSH_PASSPHRASE"
+
+// defaultIdentityFiles are tried after the agent, in the same order as ssh does.
+var defaultIdentityFiles = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// sshAuth wraps an ssh auth method with a description for error messages and
+// the host key settings resolved from auth.known_hosts.
+type sshAuth struct {
+	ssh.AuthMethod
+	method            string
+	hostKeyAlgorithms []string
+}
+
+func (a *sshAuth) ClientConfig() (*cryptossh.ClientConfig, error) {
+	cfg, err := a.AuthMethod.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(a.hostKeyAlgorithms) > 0 {
+		cfg.HostKeyAlgorithms = a.hostKeyAlgorithms
+	}
+	return cfg, nil
+}
+
+// newSSHAuth picks the credentials for an ssh remote: an explicitly configured key,
+// then the ssh agent, then the IdentityFile from ~/.ssh/config and the default keys.
+func newSSHAuth(ep *transport.Endpoint) (transport.AuthMethod, error) {
+	user := firstNonEmpty(stashconfig.SSHUser, ep.User, sshConfigValue(ep.Host, "User"), "git")
+	hostKeys, algorithms, err := knownHostsCallback(ep)
+	if err != nil {
+		return nil, err
+	}
+
+	// A configured key is never skipped silently; failing to load it is an error.
+	if keyFile := stashconfig.SSHKeyFile; keyFile != "" {
+		return keyFileAuth(user, expandHome(keyFile), hostKeys, algorithms)
+	}
+
+	var tried []string
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		tried = append(tried, "ssh agent (SSH_AUTH_SOCK not set)")
+	} else if agent, err := ssh.NewSSHAgentAuth(user); err != nil {
+		tried = append(tried, fmt.Sprintf("ssh agent (%v)", err))
+	} else {
+		agent.HostKeyCallback = hostKeys
+		return &sshAuth{AuthMethod: agent, method: "ssh agent as " + user, hostKeyAlgorithms: algorithms}, nil
+	}
+
+	var files []string
+	for _, keyFile := range identityFiles(ep.Host) {
+		if _, err := os.Stat(keyFile); err == nil {
+			return keyFileAuth(user, keyFile, hostKeys, algorithms)
+		}
+		files = append(files, keyFile)
+	}
+	tried = append(tried, "key files "+strings.Join(files, ", "))
+	return nil, fmt.Errorf("no ssh credentials for %s: tried %s; set auth.ssh_key or %s",
+		ep.Host, strings.Join(tried, " and "), stashconfig.SSHKeyEnvVar)
+}
+
+func keyFileAuth(user, keyFile string, hostKeys cryptossh.HostKeyCallback, algorithms []string) (transport.AuthMethod, error) {
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ssh key %s: %w", keyFile, err)
+	}
+	var passphrase string
+	var missing *cryptossh.PassphraseMissingError
+	if _, err := cryptossh.ParsePrivateKey(pem); errors.As(err, &missing) {
+		if passphrase, err = keyPassphrase(keyFile); err != nil {
+			return nil, err
+		}
+	}
+	keys, err := ssh.NewPublicKeys(user, pem, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("ssh key %s: %w", keyFile, err)
+	}
+	keys.HostKeyCallback = hostKeys
+	method := fmt.Sprintf("ssh key %s as %s", keyFile, user)
+	return &sshAuth{AuthMethod: keys, method: method, hostKeyAlgorithms: algorithms}, nil
+}
+
+// keyPassphrase reads the passphrase of an encrypted key from the environment or,
+// when attached to a terminal, prompts for it.
+func keyPassphrase(keyFile string) (string, error) {
+	if passphrase := os.Getenv(SSHPassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	return promptPassphrase(keyFile)
+}
+
+// promptPassphrase asks for the passphrase of keyFile on the terminal.
+var promptPassphrase = func(keyFile string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("ssh key %s is encrypted: set %s", keyFile, SSHPassphraseEnvVar)
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", keyFile)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// knownHostsCallback verifies host keys against auth.known_hosts. Without it the
+// callback is left nil and go-git falls back to SSH_KNOWN_HOSTS and ~/.ssh/known_hosts.
+func knownHostsCallback(ep *transport.Endpoint) (cryptossh.HostKeyCallback, []string, error) {
+	if stashconfig.KnownHostsFile == "" {
+		return nil, nil, nil
+	}
+	file := expandHome(stashconfig.KnownHostsFile)
+	db, err := knownhosts.NewDB(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("known_hosts %s: %w", file, err)
+	}
+	return db.HostKeyCallback(), db.HostKeyAlgorithms(sshHostWithPort(ep)), nil
+}
+
+// sshHostWithPort resolves the address like go-git does, honouring Hostname and Port from ~/.ssh/config.
+func sshHostWithPort(ep *transport.Endpoint) string {
+	host := firstNonEmpty(sshConfigValue(ep.Host, "Hostname"), ep.Host)
+	port := ep.Port
+	if port == 0 {
+		port, _ = strconv.Atoi(sshConfigValue(ep.Host, "Port"))
+	}
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func identityFiles(host string) []string {
+	var files []string
+	if file := sshConfigValue(host, "IdentityFile"); file != "" {
+		files = append(files, expandHome(file))
+	}
+	for _, file := range defaultIdentityFiles {
+		if file = expandHome(file); !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func sshConfigValue(host, key string) string {
+	if ssh.DefaultSSHConfig == nil {
+		return ""
+	}
+	return strings.TrimSpace(ssh.DefaultSSHConfig.Get(host, key))
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,221 @@
+package gitx
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v6/plumbing/transport/ssh/knownhosts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cryptossh "golang.org/x/crypto/ssh"
+
+	stashconfig "8stash/internal/config"
+	"8stash/internal/test"
+)
+
+// isolateSSHConfig keeps the agent, keys and ~/.ssh/config of the machine running the tests out of the way.
+func isolateSSHConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(SSHPassphraseEnvVar, "")
+	origSSHConfig := ssh.DefaultSSHConfig
+	origKey, origUser, origKnownHosts := stashconfig.SSHKeyFile, stashconfig.SSHUser, stashconfig.KnownHostsFile
+	ssh.DefaultSSHConfig = nil
+	clear(authCache)
+	t.Cleanup(func() {
+		clear(authCache)
+		ssh.DefaultSSHConfig = origSSHConfig
+		stashconfig.SSHKeyFile, stashconfig.SSHUser, stashconfig.KnownHostsFile = origKey, origUser, origKnownHosts
+	})
+}
+
+// writeSSHKey writes a new ed25519 private key, encrypted when passphrase is not empty.
+func writeSSHKey(t *testing.T, passphrase string) (string, cryptossh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = cryptossh.MarshalPrivateKey(priv, "")
+	} else {
+		block, err = cryptossh.MarshalPrivateKeyWithPassphrase(priv, "", []byte(passphrase))
+	}
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	sshPub, err := cryptossh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return path, sshPub
+}
+
+func sshEndpoint(t *testing.T, url string) *transport.Endpoint {
+	t.Helper()
+	ep, err := transport.NewEndpoint(url)
+	require.NoError(t, err)
+	return ep
+}
+
+func TestNewSSHAuth_ConfiguredKeyAndUser(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	keyFile, _ := writeSSHKey(t, "")
+	stashconfig.SSHKeyFile = keyFile
+	stashconfig.SSHUser = "deploy"
+
+	// Act
+	auth, err := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+
+	// Assert
+	require.NoError(t, err)
+	require.IsType(t, &sshAuth{}, auth)
+	assert.Equal(t, "ssh key "+keyFile+" as deploy", auth.(*sshAuth).method)
+}
+
+func TestNewSSHAuth_EncryptedKey_UsesPassphraseFromEnvironment(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	keyFile, _ := writeSSHKey(t, "correct horse")
+	stashconfig.SSHKeyFile = keyFile
+
+	// Act
+	_, errWithout := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+	t.Setenv(SSHPassphraseEnvVar, "correct horse")
+	auth, err := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+
+	// Assert
+	require.Error(t, errWithout)
+	assert.Contains(t, errWithout.Error(), "is encrypted: set "+SSHPassphraseEnvVar)
+	require.NoError(t, err)
+	assert.Equal(t, "ssh key "+keyFile+" as git", auth.(*sshAuth).method)
+}
+
+func TestNewSSHAuth_MissingConfiguredKey_ReturnsError(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	stashconfig.SSHKeyFile = filepath.Join(t.TempDir(), "missing")
+
+	// Act
+	_, err := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ssh key "+stashconfig.SSHKeyFile)
+}
+
+func TestNewSSHAuth_NoCredentials_ListsWhatWasTried(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+
+	// Act
+	_, err := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ssh agent (SSH_AUTH_SOCK not set)")
+	assert.Contains(t, err.Error(), "id_ed25519")
+	assert.Contains(t, err.Error(), stashconfig.SSHKeyEnvVar)
+}
+
+func TestNewSSHAuth_DefaultKeyInHome(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	keyFile, _ := writeSSHKey(t, "")
+	pem, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".ssh"), 0o700))
+	defaultKey := filepath.Join(home, ".ssh", "id_rsa")
+	require.NoError(t, os.WriteFile(defaultKey, pem, 0o600))
+
+	// Act
+	auth, err := newSSHAuth(sshEndpoint(t, "ssh://example.com/team/repo.git"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "ssh key "+defaultKey+" as git", auth.(*sshAuth).method)
+}
+
+func TestNewSSHAuth_KnownHostsFile_VerifiesHostKey(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	keyFile, _ := writeSSHKey(t, "")
+	_, hostKey := writeSSHKey(t, "")
+	_, otherKey := writeSSHKey(t, "")
+	stashconfig.SSHKeyFile = keyFile
+	stashconfig.KnownHostsFile = filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"example.com"}, hostKey) + "\n"
+	require.NoError(t, os.WriteFile(stashconfig.KnownHostsFile, []byte(line), 0o600))
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+
+	// Act
+	auth, err := newSSHAuth(sshEndpoint(t, "git@example.com:team/repo.git"))
+	require.NoError(t, err)
+	cfg, err := auth.(*sshAuth).ClientConfig()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{cryptossh.KeyAlgoED25519}, cfg.HostKeyAlgorithms)
+	assert.NoError(t, cfg.HostKeyCallback("example.com:22", addr, hostKey))
+	assert.Error(t, cfg.HostKeyCallback("example.com:22", addr, otherKey))
+}
+
+func TestUpdateRepository_SSHFailure_NamesAuthMethod(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	keyFile, _ := writeSSHKey(t, "")
+	stashconfig.SSHKeyFile = keyFile
+	setRemoteURL(t, repo, "ssh://git@127.0.0.1:1/team/repo.git")
+
+	// Act
+	err = UpdateRepository()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth: ssh key "+keyFile+" as git")
+}
+
+func TestRemoteAuth_EncryptedKey_PromptsOncePerRemote(t *testing.T) {
+	// Arrange
+	isolateSSHConfig(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	keyFile, _ := writeSSHKey(t, "correct horse")
+	stashconfig.SSHKeyFile = keyFile
+	setRemoteURL(t, repo, "ssh://git@127.0.0.1:1/team/repo.git")
+
+	prompts := 0
+	origPrompt := promptPassphrase
+	promptPassphrase = func(string) (string, error) {
+		prompts++
+		return "correct horse", nil
+	}
+	defer func() { promptPassphrase = origPrompt }()
+
+	// Act
+	first, err := remoteAuth(repo, "origin")
+	require.NoError(t, err)
+	second, err := remoteAuth(repo, "origin")
+	require.NoError(t, err)
+	_ = FetchStashes("8stash/")
+	_ = UpdateRepository()
+
+	// Assert
+	assert.Equal(t, 1, prompts)
+	assert.Same(t, first, second)
+}
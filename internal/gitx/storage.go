@@ -0,0 +1,229 @@
+package gitx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+
+	stashconfig "8stash/internal/config"
+	"8stash/internal/naming"
+)
+
+// trackingRefPrefix holds the local copies of remote stashes in ref storage mode,
+// e.g. refs/8stash-remotes/origin/8stash/1234.
+const trackingRefPrefix = "refs/8stash-remotes/"
+
+func useRefStorage() bool {
+	return stashconfig.Storage == stashconfig.StorageRefs
+}
+
+// stashRemoteRef is the reference a stash is pushed to on the remote.
+func stashRemoteRef(name string) plumbing.ReferenceName {
+	return storageRemoteRef(useRefStorage(), name)
+}
+
+// stashTrackingRef is the local copy of a stash fetched from remote.
+func stashTrackingRef(remote, name string) plumbing.ReferenceName {
+	return storageTrackingRef(useRefStorage(), remote, name)
+}
+
+// storageRemoteRef is the remote reference of a stash in ref storage when refs is set,
+// in branch storage otherwise, whatever storage is configured.
+func storageRemoteRef(refs bool, name string) plumbing.ReferenceName {
+	if refs {
+		return plumbing.ReferenceName("refs/" + name)
+	}
+	return plumbing.NewBranchReferenceName(name)
+}
+
+// storageTrackingRef is the tracking reference of a stash like storageRemoteRef.
+func storageTrackingRef(refs bool, remote, name string) plumbing.ReferenceName {
+	if refs {
+		return plumbing.ReferenceName(trackingRefPrefix + remote + "/" + name)
+	}
+	return plumbing.NewRemoteReferenceName(remote, name)
+}
+
+// trackedStashName returns the stash name of a tracking reference of remote.
+func trackedStashName(ref *plumbing.Reference, remote string) (string, bool) {
+	if !useRefStorage() {
+		return remoteBranchName(ref, remote)
+	}
+	if ref.Type() != plumbing.HashReference {
+		return "", false
+	}
+	return strings.CutPrefix(ref.Name().String(), trackingRefPrefix+remote+"/")
+}
+
+// stashFetchRefSpec maps the stashes below prefix on the remote to their tracking refs.
+// It is not forced with a leading +, because go-git prunes with the reversed refspec and
+// would then look for the stashes under "+refs/..." and prune every one of them; the
+// fetch forces its updates instead.
+func stashFetchRefSpec(remote, prefix string) config.RefSpec {
+	return config.RefSpec(stashRemoteRef(prefix).String() + "*:" + stashTrackingRef(remote, prefix).String() + "*")
+}
+
+// fetchStashRefs fetches only the stashes below prefix and prunes tracking refs of
+// stashes that no longer exist on the remote. The checked-out branch is not touched.
+// Pruned stashes are reported on stderr so machine readable output stays intact.
+func fetchStashRefs(repo *git.Repository, remote, prefix string) error {
+	before, err := trackedStashNames(repo, remote, prefix)
+	if err != nil {
+		return err
+	}
+	auth, err := remoteAuth(repo, remote)
+	if err != nil {
+		return err
+	}
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{stashFetchRefSpec(remote, prefix)},
+		Prune:      true,
+		// A sequential id may be reused for a new stash once the old one is dropped.
+		Force: true,
+		Auth:  auth,
+	})
+	switch {
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		// A remote without any refs holds no stashes, e.g. a freshly created stash remote.
+		for _, name := range before {
+			if err := repo.Storer.RemoveReference(stashTrackingRef(remote, name)); err != nil {
+				return fmt.Errorf("prune stash %s: %w", name, err)
+			}
+		}
+	case err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate):
+		return fmt.Errorf("fetch stashes: %w", withAuthMethod(err, auth))
+	}
+	after, err := trackedStashNames(repo, remote, prefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range before {
+		if !slices.Contains(after, name) {
+			fmt.Fprintf(os.Stderr, "Stash %s no longer exists on %s, removed it locally\n", name, remote)
+		}
+	}
+	return nil
+}
+
+func trackedStashNames(repo *git.Repository, remote, prefix string) ([]string, error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	defer refs.Close()
+
+	var names []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if name, ok := trackedStashName(ref, remote); ok && naming.IsStashBranch(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error processing references: %w", err)
+	}
+	return names, nil
+}
+
+// FetchStashes updates the tracking refs of all stashes below prefix from the remote.
+func FetchStashes(prefix string) error {
+	repo, _, _, remote, err := getRepoContext()
+	if err != nil {
+		return err
+	}
+	return fetchStashRefs(repo, remote, prefix)
+}
+
+// trackPushedStash records a stash that was pushed in ref storage mode and removes
+// the temporary local branch it was committed on, so it never shows up as a branch.
+func trackPushedStash(repo *git.Repository, remote, name string, hash plumbing.Hash) error {
+	if !useRefStorage() {
+		return nil
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(stashTrackingRef(remote, name), hash)); err != nil {
+		return fmt.Errorf("track stash %s: %w", name, err)
+	}
+	err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(name))
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("remove local branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// MigratedStash is a stash moved from a branch into the ref namespace.
+type MigratedStash struct {
+	Name string
+	// Ref is the reference the stash now has on the remote.
+	Ref string
+}
+
+// MigrateBranchStashes moves every branch based stash starting with prefix into the
+// ref namespace on the remote and returns the migrated stashes. The destination is
+// always ref storage, as the config usually still says branches while migrating.
+func MigrateBranchStashes(prefix string) ([]MigratedStash, error) {
+	repo, _, _, remote, err := getRepoContext()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get references: %w", err)
+	}
+	defer refs.Close()
+
+	var branches []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if name, ok := remoteBranchName(ref, remote); ok && naming.IsStashBranch(name, prefix) {
+			branches = append(branches, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error processing references: %w", err)
+	}
+
+	var migrated []MigratedStash
+	for _, ref := range branches {
+		name, _ := remoteBranchName(ref, remote)
+		target := storageRemoteRef(true, name)
+		branch := storageRemoteRef(false, name)
+		// Copy first and delete afterwards so a failure never loses a stash.
+		copySpec := config.RefSpec("+" + ref.Name().String() + ":" + target.String())
+		if err := pushRefSpec(remote, repo, copySpec); err != nil {
+			return migrated, fmt.Errorf("migrate %s: %w", name, err)
+		}
+		if err := pushRefSpec(remote, repo, config.RefSpec(":"+branch.String())); err != nil {
+			return migrated, fmt.Errorf("remove branch %s: %w", name, err)
+		}
+		tracking := plumbing.NewHashReference(storageTrackingRef(true, remote, name), ref.Hash())
+		if err := repo.Storer.SetReference(tracking); err != nil {
+			return migrated, fmt.Errorf("track stash %s: %w", name, err)
+		}
+		for _, old := range []plumbing.ReferenceName{ref.Name(), branch} {
+			if err := removeUnlessHead(repo, old); err != nil {
+				return migrated, err
+			}
+		}
+		migrated = append(migrated, MigratedStash{Name: name, Ref: target.String()})
+	}
+	return migrated, nil
+}
+
+func removeUnlessHead(repo *git.Repository, name plumbing.ReferenceName) error {
+	if head, err := repo.Head(); err == nil && head.Name() == name {
+		return nil
+	}
+	err := repo.Storer.RemoveReference(name)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("remove %s: %w", name, err)
+	}
+	return nil
+}
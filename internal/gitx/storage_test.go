@@ -0,0 +1,213 @@
+package gitx
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	stashconfig "8stash/internal/config"
+	"8stash/internal/test"
+)
+
+func useRefStorageInTest(t *testing.T) {
+	t.Helper()
+	orig := stashconfig.Storage
+	stashconfig.Storage = stashconfig.StorageRefs
+	t.Cleanup(func() { stashconfig.Storage = orig })
+}
+
+func remoteRefNames(t *testing.T, repo *git.Repository) map[string]plumbing.Hash {
+	t.Helper()
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	names := make(map[string]plumbing.Hash)
+	for _, r := range refs {
+		names[r.Name().String()] = r.Hash()
+	}
+	return names
+}
+
+func TestRefStorage_PushListAndDelete(t *testing.T) {
+	// Arrange
+	useRefStorageInTest(t)
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+	stashName := "8stash/1234"
+
+	// Act
+	err = StashChangesToNewBranch(stashName, StashOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	remoteRefs := remoteRefNames(t, repo)
+	assert.Contains(t, remoteRefs, "refs/8stash/1234")
+	assert.NotContains(t, remoteRefs, "refs/heads/8stash/1234")
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(stashName), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound, "no local branch should be left behind")
+
+	// Act - a fresh fetch picks the stash up through the explicit refspec
+	require.NoError(t, repo.Storer.RemoveReference("refs/8stash-remotes/origin/8stash/1234"))
+	require.NoError(t, UpdateRepository())
+	stashes, err := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "1234", stashes[0].ID)
+	assert.Equal(t, stashName, stashes[0].Branch)
+
+	// Act
+	err = DeleteBranch(stashName)
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotContains(t, remoteRefNames(t, repo), "refs/8stash/1234")
+	stashes, err = ListStashes("8stash/")
+	require.NoError(t, err)
+	assert.Empty(t, stashes)
+}
+
+func TestMigrateBranchStashes_MovesBranchesIntoRefNamespace(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/111", "a.txt", "A", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "feature/other", "b.txt", "B", time.Now())
+	test.FetchAll(t, repo)
+	before := remoteRefNames(t, repo)
+
+	// Act
+	migrated, err := MigrateBranchStashes("8stash/")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []MigratedStash{{Name: "8stash/111", Ref: "refs/8stash/111"}}, migrated)
+	_, err = repo.Reference(plumbing.ReferenceName("refs/8stash-remotes/origin/8stash/111"), false)
+	assert.NoError(t, err, "the migrated stash is tracked like a fetched ref stash")
+
+	after := remoteRefNames(t, repo)
+	assert.NotContains(t, after, "refs/heads/8stash/111")
+	assert.Equal(t, before["refs/heads/8stash/111"], after["refs/8stash/111"], "stash commit must be kept")
+	assert.Contains(t, after, "refs/heads/feature/other", "other branches are left alone")
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName("8stash/111"), false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+	useRefStorageInTest(t)
+	stashes, err := ListStashes("8stash/")
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "111", stashes[0].ID)
+}
+
+func TestUpdateRepository_PrunesStashesDroppedOnRemoteAndReportsThem(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/gone", "a.txt", "A", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/kept", "b.txt", "B", time.Now())
+	test.FetchAll(t, repo)
+
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	remote, err := git.PlainOpen(cfg.Remotes["origin"].URLs[0])
+	require.NoError(t, err)
+	require.NoError(t, remote.Storer.RemoveReference(plumbing.NewBranchReferenceName("8stash/gone")))
+
+	origStderr := os.Stderr
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stderr = w
+
+	// Act
+	err = UpdateRepository()
+
+	// Assert
+	_ = w.Close()
+	os.Stderr = origStderr
+	report, readErr := io.ReadAll(r)
+	require.NoError(t, readErr)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(report), "Stash 8stash/gone no longer exists on origin")
+	assert.NotContains(t, string(report), "8stash/kept")
+
+	stashes, err := ListStashes("8stash/")
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "kept", stashes[0].ID)
+}
+
+// packRefs moves the loose refs of a repository into packed-refs, like git clone does.
+func packRefs(t *testing.T, localPath string) {
+	t.Helper()
+	st := filesystem.NewStorage(osfs.New(filepath.Join(localPath, ".git")), cache.NewObjectLRUDefault())
+	require.NoError(t, st.PackRefs())
+	_, err := os.Stat(filepath.Join(localPath, ".git", "packed-refs"))
+	require.NoError(t, err)
+}
+
+func TestFetchStashes_WithPackedRefs_KeepsAndPrunesStashes(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/gone", "a.txt", "A", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "8stash/kept", "b.txt", "B", time.Now())
+	test.FetchAll(t, repo)
+	packRefs(t, localPath)
+
+	// Act
+	errUnchanged := FetchStashes("8stash/")
+	stashesUnchanged, listErr := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, errUnchanged)
+	require.NoError(t, listErr)
+	assert.Len(t, stashesUnchanged, 2)
+
+	// Arrange
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	remote, err := git.PlainOpen(cfg.Remotes["origin"].URLs[0])
+	require.NoError(t, err)
+	require.NoError(t, remote.Storer.RemoveReference(plumbing.NewBranchReferenceName("8stash/gone")))
+	packRefs(t, localPath)
+
+	// Act
+	errPruned := FetchStashes("8stash/")
+	stashes, listErr := ListStashes("8stash/")
+
+	// Assert
+	require.NoError(t, errPruned)
+	require.NoError(t, listErr)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "kept", stashes[0].ID)
+}
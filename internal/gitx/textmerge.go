@@ -0,0 +1,153 @@
+package gitx
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v6/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// hunk replaces the base lines [start, end) with lines.
+type hunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+// mergeText performs a line based three-way merge of ours and theirs against base.
+// Overlapping or adjacent changes that differ are written as a conflict block with
+// git style markers, in which case clean is false.
+func mergeText(base, ours, theirs, oursLabel, theirsLabel string) (merged string, clean bool) {
+	baseLines := splitLines(base)
+	oursHunks := computeHunks(base, ours)
+	theirsHunks := computeHunks(base, theirs)
+
+	var out strings.Builder
+	clean = true
+	pos := 0
+	i, j := 0, 0
+	for i < len(oursHunks) || j < len(theirsHunks) {
+		// Start a group with the hunk that begins first and pull in everything overlapping it.
+		var groupOurs, groupTheirs []hunk
+		var start, end int
+		if j >= len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].start <= theirsHunks[j].start) {
+			start, end = oursHunks[i].start, oursHunks[i].end
+			groupOurs = append(groupOurs, oursHunks[i])
+			i++
+		} else {
+			start, end = theirsHunks[j].start, theirsHunks[j].end
+			groupTheirs = append(groupTheirs, theirsHunks[j])
+			j++
+		}
+		for {
+			if i < len(oursHunks) && oursHunks[i].start <= end {
+				groupOurs = append(groupOurs, oursHunks[i])
+				end = max(end, oursHunks[i].end)
+				i++
+				continue
+			}
+			if j < len(theirsHunks) && theirsHunks[j].start <= end {
+				groupTheirs = append(groupTheirs, theirsHunks[j])
+				end = max(end, theirsHunks[j].end)
+				j++
+				continue
+			}
+			break
+		}
+
+		writeLines(&out, baseLines[pos:start])
+		pos = end
+
+		oursText := applyHunks(baseLines, start, end, groupOurs)
+		theirsText := applyHunks(baseLines, start, end, groupTheirs)
+		switch {
+		case len(groupTheirs) == 0:
+			writeLines(&out, oursText)
+		case len(groupOurs) == 0:
+			writeLines(&out, theirsText)
+		case strings.Join(oursText, "") == strings.Join(theirsText, ""):
+			writeLines(&out, oursText)
+		default:
+			clean = false
+			out.WriteString("<<<<<<< " + oursLabel + "\n")
+			writeLines(&out, terminate(oursText))
+			out.WriteString("=======\n")
+			writeLines(&out, terminate(theirsText))
+			out.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+	}
+	writeLines(&out, baseLines[pos:])
+	return out.String(), clean
+}
+
+// computeHunks turns the line diff between base and changed into replacement hunks.
+func computeHunks(base, changed string) []hunk {
+	var hunks []hunk
+	var current *hunk
+	pos := 0
+	for _, d := range diff.Do(base, changed) {
+		lines := splitLines(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			pos += len(lines)
+		case diffmatchpatch.DiffDelete:
+			if current == nil {
+				current = &hunk{start: pos, end: pos}
+			}
+			pos += len(lines)
+			current.end = pos
+		case diffmatchpatch.DiffInsert:
+			if current == nil {
+				current = &hunk{start: pos, end: pos}
+			}
+			current.lines = append(current.lines, lines...)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// applyHunks returns the base lines [start, end) with the given hunks applied.
+func applyHunks(baseLines []string, start, end int, hunks []hunk) []string {
+	var out []string
+	pos := start
+	for _, h := range hunks {
+		out = append(out, baseLines[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, baseLines[pos:end]...)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// terminate makes sure the last line ends with a newline so conflict markers start on their own line.
+func terminate(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	out := append([]string{}, lines...)
+	out[len(out)-1] += "\n"
+	return out
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+	}
+}
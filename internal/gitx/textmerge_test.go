@@ -0,0 +1,70 @@
+package gitx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeText_NonOverlappingChanges_MergesCleanly(t *testing.T) {
+	// Arrange
+	base := "one\ntwo\nthree\nfour\nfive\n"
+	ours := "ONE\ntwo\nthree\nfour\nfive\n"
+	theirs := "one\ntwo\nthree\nfour\nFIVE\n"
+
+	// Act
+	merged, clean := mergeText(base, ours, theirs, "HEAD", "stash")
+
+	// Assert
+	assert.True(t, clean)
+	assert.Equal(t, "ONE\ntwo\nthree\nfour\nFIVE\n", merged)
+}
+
+func TestMergeText_SameChangeOnBothSides_MergesCleanly(t *testing.T) {
+	// Arrange
+	base := "a\nb\nc\n"
+	changed := "a\nB\nc\n"
+
+	// Act
+	merged, clean := mergeText(base, changed, changed, "HEAD", "stash")
+
+	// Assert
+	assert.True(t, clean)
+	assert.Equal(t, changed, merged)
+}
+
+func TestMergeText_ConflictingChanges_WritesMarkers(t *testing.T) {
+	// Arrange
+	base := "a\nb\nc\n"
+	ours := "a\nours\nc\n"
+	theirs := "a\ntheirs\nc\n"
+
+	// Act
+	merged, clean := mergeText(base, ours, theirs, "HEAD", "origin/8stash/1")
+
+	// Assert
+	assert.False(t, clean)
+	assert.Equal(t, "a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> origin/8stash/1\nc\n", merged)
+}
+
+func TestMergeText_AddAddWithoutTrailingNewline_TerminatesConflictSides(t *testing.T) {
+	// Act
+	merged, clean := mergeText("", "ours", "theirs", "HEAD", "stash")
+
+	// Assert
+	assert.False(t, clean)
+	assert.Equal(t, "<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> stash\n", merged)
+}
+
+func TestMergeText_OnlyOneSideChanged_TakesThatSide(t *testing.T) {
+	// Arrange
+	base := "a\nb\n"
+	theirs := "a\nb\nc\n"
+
+	// Act
+	merged, clean := mergeText(base, base, theirs, "HEAD", "stash")
+
+	// Assert
+	assert.True(t, clean)
+	assert.Equal(t, theirs, merged)
+}
@@ -10,15 +10,125 @@ import (
 	"8stash/internal/config"
 )
 
-func BuildStashHash() (string, error){
-	if config.NamingHashType == config.HashUUID {
-		hash, err := buildUUIDHash()
+// maxAttempts bounds how often a taken id is drawn again before giving up.
+const maxAttempts = 32
+
+// exhaustedPercent is the share of numeric or word ids in use at which no new id is drawn.
+const exhaustedPercent = 90
+
+// BuildStashHash returns a new stash branch name laid out by naming.template whose
+// id is not taken by any of the existing branch names.
+func BuildStashHash(existing []string, fields Fields) (string, error) {
+	taken := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		if IsStashBranch(name, config.BranchPrefix) {
+			taken[StashID(name, config.BranchPrefix)] = true
+		}
+	}
+	if err := checkCapacity(taken); err != nil {
+		return "", err
+	}
+	if config.NamingHashType == config.HashSequential {
+		return render(nextSequentialID(taken), fields), nil
+	}
+
+	for range maxAttempts {
+		hash, err := buildHash()
 		if err != nil {
 			return "", err
 		}
-		return config.BranchPrefix + hash, nil
+		if !taken[hash] {
+			return render(hash, fields), nil
+		}
+	}
+	// Random draws keep colliding when most ids are in use; pick one of the free ones.
+	if free := freeIDs(taken); len(free) > 0 {
+		return render(free[rand.Intn(len(free))], fields), nil
+	}
+	return "", fmt.Errorf("no free stash id found after %d attempts; %s", maxAttempts, exhaustedHint())
+}
+
+func buildHash() (string, error) {
+	switch config.NamingHashType {
+	case config.HashUUID:
+		return buildUUIDHash()
+	case config.HashWords:
+		return buildWordsHash(), nil
+	default:
+		return buildNumericHash(), nil
+	}
+}
+
+// checkCapacity fails once most numeric or word ids are in use, where random
+// draws would mostly collide. UUIDs never run out.
+func checkCapacity(taken map[string]bool) error {
+	var size int
+	var isID func(string) bool
+	switch config.NamingHashType {
+	case config.HashNumeric:
+		size, isID = config.HashRange, isNumericID
+	case config.HashWords:
+		size, isID = wordsIDCount(), isWordsID
+	default:
+		return nil
+	}
+
+	used := 0
+	for id := range taken {
+		if isID(id) {
+			used++
+		}
+	}
+	if used*100 >= size*exhaustedPercent {
+		return fmt.Errorf("stash ids nearly exhausted: %d of %d %s ids in use; %s", used, size, config.NamingHashType, exhaustedHint())
 	}
-	return config.BranchPrefix + buildNumericHash(), nil
+	return nil
+}
+
+// freeIDs lists the numeric or word ids that are not taken. UUIDs are not listed.
+func freeIDs(taken map[string]bool) []string {
+	var free []string
+	switch config.NamingHashType {
+	case config.HashNumeric:
+		for i := 0; i < config.HashRange; i++ {
+			if id := strconv.Itoa(i); !taken[id] {
+				free = append(free, id)
+			}
+		}
+	case config.HashWords:
+		for _, adjective := range adjectives {
+			for _, noun := range nouns {
+				if id := adjective + "-" + noun; !taken[id] {
+					free = append(free, id)
+				}
+			}
+		}
+	}
+	return free
+}
+
+func exhaustedHint() string {
+	if config.NamingHashType == config.HashWords {
+		return "run '8stash cleanup' or switch naming.hash_type to uuid"
+	}
+	return "run '8stash cleanup' or raise naming.hash_numeric_max_value"
+}
+
+// nextSequentialID returns one above the highest id in use, so ids only grow while
+// stashes exist and start again at 1 once the remote holds none.
+func nextSequentialID(taken map[string]bool) string {
+	highest := 0
+	for id := range taken {
+		if n, err := strconv.Atoi(id); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return strconv.Itoa(highest + 1)
+}
+
+func isNumericID(id string) bool {
+	n, err := strconv.Atoi(id)
+	return err == nil && n >= 0 && n < config.HashRange
 }
 
 func buildNumericHash() string {
@@ -33,4 +143,3 @@ func buildUUIDHash() (string, error) {
 	}
 	return randomUUID.String(), nil
 }
-
@@ -13,7 +13,7 @@ import (
 )
 
 func TestBuildStashHash(t *testing.T) {
-	stashHash, err := BuildStashHash()
+	stashHash, err := BuildStashHash(nil, Fields{})
 	if err != nil {
 		t.Error("Error building Hash")
 	}
@@ -44,7 +44,7 @@ func TestBuildStashHash_UUIDFormat(t *testing.T) {
 	config.NamingHashType = config.HashUUID
 	config.BranchPrefix = "8stash/"
 
-	h, err := BuildStashHash()
+	h, err := BuildStashHash(nil, Fields{})
 	require.NoError(t, err)
 	assert.True(t, strings.HasPrefix(h, config.BranchPrefix))
 	u := strings.TrimPrefix(h, config.BranchPrefix)
@@ -68,7 +68,7 @@ func TestBuildStashHash_NumericRangeAndDigits(t *testing.T) {
 
 	// sample multiple times to exercise randomness
 	for i := 0; i < 50; i++ {
-		h, err := BuildStashHash()
+		h, err := BuildStashHash(nil, Fields{})
 		require.NoError(t, err)
 		assert.True(t, strings.HasPrefix(h, config.BranchPrefix))
 		numPart := strings.TrimPrefix(h, config.BranchPrefix)
@@ -91,8 +91,134 @@ func TestBuildStashHash_RangeOneProducesZero(t *testing.T) {
 	config.HashRange = 1
 	config.BranchPrefix = "8stash/"
 
-	h, err := BuildStashHash()
+	h, err := BuildStashHash(nil, Fields{})
 	require.NoError(t, err)
 	numPart := strings.TrimPrefix(h, config.BranchPrefix)
 	assert.Equal(t, "0", numPart, "with range 1 rand.Intn(1) yields 0")
 }
+
+func TestBuildStashHash_SkipsExistingIDs(t *testing.T) {
+	origType := config.NamingHashType
+	origRange := config.HashRange
+	origPrefix := config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingHashType = origType
+		config.HashRange = origRange
+		config.BranchPrefix = origPrefix
+	})
+
+	config.NamingHashType = config.HashNumeric
+	config.HashRange = 20
+	config.BranchPrefix = "8stash/"
+	var existing []string
+	for i := 0; i < 17; i++ {
+		existing = append(existing, config.BranchPrefix+strconv.Itoa(i))
+	}
+
+	for i := 0; i < 20; i++ {
+		h, err := BuildStashHash(existing, Fields{})
+		require.NoError(t, err)
+		assert.NotContains(t, existing, h)
+	}
+}
+
+func TestBuildStashHash_NearlyExhausted_ReturnsError(t *testing.T) {
+	origType := config.NamingHashType
+	origRange := config.HashRange
+	origPrefix := config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingHashType = origType
+		config.HashRange = origRange
+		config.BranchPrefix = origPrefix
+	})
+
+	config.NamingHashType = config.HashNumeric
+	config.HashRange = 10
+	config.BranchPrefix = "8stash/"
+	existing := []string{"8stash/other", "feature/3"}
+	for i := 0; i < 9; i++ {
+		existing = append(existing, config.BranchPrefix+strconv.Itoa(i))
+	}
+
+	_, err := BuildStashHash(existing, Fields{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "9 of 10 numeric ids in use")
+}
+
+func TestBuildStashHash_WordsFormat(t *testing.T) {
+	origType := config.NamingHashType
+	origPrefix := config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingHashType = origType
+		config.BranchPrefix = origPrefix
+	})
+
+	config.NamingHashType = config.HashWords
+	config.BranchPrefix = "8stash/"
+
+	for i := 0; i < 50; i++ {
+		h, err := BuildStashHash([]string{"8stash/brave-otter"}, Fields{})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(h, config.BranchPrefix))
+		id := strings.TrimPrefix(h, config.BranchPrefix)
+		assert.True(t, isWordsID(id), "expected adjective-noun id, got %q", id)
+		assert.NotEqual(t, "brave-otter", id)
+	}
+}
+
+func TestBuildStashHash_WordsNearlyExhausted_ReturnsError(t *testing.T) {
+	origType := config.NamingHashType
+	origPrefix := config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingHashType = origType
+		config.BranchPrefix = origPrefix
+	})
+
+	config.NamingHashType = config.HashWords
+	config.BranchPrefix = "8stash/"
+	var existing []string
+	for _, adjective := range adjectives {
+		for _, noun := range nouns {
+			existing = append(existing, config.BranchPrefix+adjective+"-"+noun)
+		}
+	}
+
+	_, err := BuildStashHash(existing, Fields{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "words ids in use")
+	assert.Contains(t, err.Error(), "switch naming.hash_type to uuid")
+}
+
+func TestWordLists_AreUniqueAndHyphenFree(t *testing.T) {
+	for _, list := range [][]string{adjectives, nouns} {
+		seen := map[string]bool{}
+		for _, w := range list {
+			assert.False(t, seen[w], "duplicate word %q", w)
+			assert.NotContains(t, w, "-")
+			assert.Equal(t, strings.ToLower(w), w)
+			seen[w] = true
+		}
+	}
+}
+
+func TestBuildStashHash_SequentialUsesNextID(t *testing.T) {
+	origType := config.NamingHashType
+	origPrefix := config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingHashType = origType
+		config.BranchPrefix = origPrefix
+	})
+
+	config.NamingHashType = config.HashSequential
+	config.BranchPrefix = "8stash/"
+
+	first, err := BuildStashHash(nil, Fields{})
+	require.NoError(t, err)
+	next, err := BuildStashHash([]string{"8stash/1", "8stash/7", "8stash/brave-otter", "feature/99"}, Fields{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "8stash/1", first)
+	assert.Equal(t, "8stash/8", next)
+}
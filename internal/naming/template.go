@@ -0,0 +1,120 @@
+package naming
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"8stash/internal/config"
+)
+
+// Fields fills the placeholders of naming.template other than {id}.
+type Fields struct {
+	User  string
+	Email string
+	Base  string
+	Date  time.Time
+}
+
+var unsafeRefChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// render returns the branch name of a stash with the given id.
+func render(id string, f Fields) string {
+	if config.NamingTemplate == "" {
+		return config.BranchPrefix + id
+	}
+	return config.PlaceholderPattern.ReplaceAllStringFunc(config.NamingTemplate, func(p string) string {
+		switch strings.Trim(p, "{}") {
+		case config.PlaceholderID:
+			return id
+		case config.PlaceholderUser:
+			return sanitizeSegment(f.User, "unknown")
+		case config.PlaceholderEmail:
+			local, _, _ := strings.Cut(f.Email, "@")
+			return sanitizeSegment(local, "unknown")
+		case config.PlaceholderBase:
+			return sanitizeSegment(f.Base, "detached")
+		case config.PlaceholderDate:
+			return f.Date.Format(time.DateOnly)
+		}
+		return p
+	})
+}
+
+// sanitizeSegment turns s into a single lower case ref path segment, e.g.
+// "Jane Doe" into jane-doe and feature/login into feature-login.
+func sanitizeSegment(s, fallback string) string {
+	s = unsafeRefChars.ReplaceAllString(strings.ToLower(s), "-")
+	s = strings.Trim(s, "-.")
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// templatePattern matches branch names laid out by naming.template and captures the id.
+func templatePattern() *regexp.Regexp {
+	t := config.NamingTemplate
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range config.PlaceholderPattern.FindAllStringSubmatchIndex(t, -1) {
+		b.WriteString(regexp.QuoteMeta(t[last:loc[0]]))
+		switch t[loc[2]:loc[3]] {
+		case config.PlaceholderID:
+			b.WriteString(`([^/]+)`)
+		case config.PlaceholderDate:
+			b.WriteString(`\d{4}-\d{2}-\d{2}`)
+		default:
+			b.WriteString(`[^/]+`)
+		}
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(t[last:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// StashID returns the id of a stash branch. Branches that do not follow naming.template,
+// e.g. stashes pushed before it was set, fall back to the part after prefix.
+func StashID(branch, prefix string) string {
+	if config.NamingTemplate != "" {
+		if m := templatePattern().FindStringSubmatch(branch); m != nil {
+			return m[1]
+		}
+	}
+	return strings.TrimPrefix(branch, prefix)
+}
+
+// IsStashBranch reports whether branch below prefix is a stash. Without naming.template
+// every branch below the prefix is one. With a template the prefix is shared with
+// ordinary branches, e.g. wip/, so the branch must follow the template, or be prefix
+// followed by an id as pushed before the template was set, and the id must be one 8stash
+// generates.
+func IsStashBranch(branch, prefix string) bool {
+	rest, ok := strings.CutPrefix(branch, prefix)
+	if !ok {
+		return false
+	}
+	if config.NamingTemplate == "" {
+		return true
+	}
+	if m := templatePattern().FindStringSubmatch(branch); m != nil {
+		return isGeneratedID(m[1])
+	}
+	return !strings.Contains(rest, "/") && isGeneratedID(rest)
+}
+
+// isGeneratedID reports whether id has the form of a numeric, uuid or words id,
+// whatever hash type is configured now.
+func isGeneratedID(id string) bool {
+	if id != "" && strings.Trim(id, "0123456789") == "" {
+		return true
+	}
+	if _, err := uuid.Parse(id); err == nil && len(id) == 36 {
+		return true
+	}
+	return isWordsID(strings.ToLower(id))
+}
@@ -0,0 +1,114 @@
+package naming
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/config"
+)
+
+func setTemplate(t *testing.T, template string) {
+	t.Helper()
+	origTemplate, origPrefix := config.NamingTemplate, config.BranchPrefix
+	t.Cleanup(func() {
+		config.NamingTemplate, config.BranchPrefix = origTemplate, origPrefix
+	})
+	config.NamingTemplate = template
+	config.BranchPrefix = config.TemplatePrefix(template)
+}
+
+func TestRender_FillsPlaceholders(t *testing.T) {
+	setTemplate(t, "wip/{user}/{email}/{base}/{date}/{id}")
+	fields := Fields{
+		User:  "Jane Doe",
+		Email: "J.Doe+work@example.com",
+		Base:  "feature/Login",
+		Date:  time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC),
+	}
+
+	name := render("42", fields)
+
+	assert.Equal(t, "wip/jane-doe/j.doe-work/feature-login/2026-03-07/42", name)
+}
+
+func TestRender_EmptyFieldsUseFallbacks(t *testing.T) {
+	setTemplate(t, "wip/{user}/{base}/{id}")
+
+	name := render("42", Fields{})
+
+	assert.Equal(t, "wip/unknown/detached/42", name)
+}
+
+func TestRender_NoTemplate_UsesBranchPrefix(t *testing.T) {
+	setTemplate(t, "")
+	config.BranchPrefix = "8stash/"
+
+	assert.Equal(t, "8stash/42", render("42", Fields{User: "jane"}))
+}
+
+func TestStashID(t *testing.T) {
+	setTemplate(t, "wip/{user}/{base}/{date}/{id}")
+
+	testCases := []struct {
+		name   string
+		branch string
+		want   string
+	}{
+		{"follows template", "wip/jane/main/2026-03-07/brave-otter", "brave-otter"},
+		{"pushed before the template was set", "wip/1234", "1234"},
+		{"date placeholder does not match", "wip/jane/main/today/7", "jane/main/today/7"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, StashID(tc.branch, config.BranchPrefix))
+		})
+	}
+}
+
+func TestIsStashBranch(t *testing.T) {
+	setTemplate(t, "wip/{user}/{id}")
+
+	testCases := []struct {
+		name   string
+		branch string
+		want   bool
+	}{
+		{"numeric id", "wip/jane/1234", true},
+		{"uuid id", "wip/jane/3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", true},
+		{"words id", "wip/jane/brave-otter", true},
+		{"pushed before the template was set", "wip/1234", true},
+		{"ordinary branch following the template", "wip/alice/real-feature", false},
+		{"ordinary branch below the prefix", "wip/refactor", false},
+		{"outside the prefix", "feature/1234", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsStashBranch(tc.branch, config.BranchPrefix))
+		})
+	}
+}
+
+func TestIsStashBranch_NoTemplate_AcceptsEveryBranchBelowPrefix(t *testing.T) {
+	setTemplate(t, "")
+	config.BranchPrefix = "8stash/"
+
+	assert.True(t, IsStashBranch("8stash/anything", config.BranchPrefix))
+	assert.False(t, IsStashBranch("feature/1234", config.BranchPrefix))
+}
+
+func TestBuildStashHash_Template_ChecksIDsAcrossUsersAndBranches(t *testing.T) {
+	setTemplate(t, "wip/{user}/{base}/{id}")
+	origType := config.NamingHashType
+	t.Cleanup(func() { config.NamingHashType = origType })
+	config.NamingHashType = config.HashSequential
+
+	name, err := BuildStashHash([]string{"wip/bob/main/1", "wip/alice/feature-x/2"}, Fields{User: "carol", Base: "main"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "wip/carol/main/3", name)
+}
@@ -0,0 +1,44 @@
+package naming
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// adjectives and nouns are short, easy to spell and distinct when read out loud.
+var adjectives = []string{
+	"amber", "bold", "brave", "brisk", "calm", "clever", "cosy", "crisp",
+	"curly", "daring", "eager", "fancy", "fast", "fluffy", "gentle", "giant",
+	"glad", "golden", "happy", "hasty", "humble", "icy", "jolly", "kind",
+	"lazy", "little", "lucky", "mellow", "merry", "mighty", "misty", "modest",
+	"noble", "odd", "plucky", "polite", "proud", "quick", "quiet", "rapid",
+	"rosy", "rusty", "shiny", "silent", "silver", "sleepy", "sly", "smooth",
+	"snowy", "sturdy", "sunny", "swift", "tidy", "tiny", "vivid", "warm",
+	"wavy", "wild", "windy", "wise", "witty", "young", "zany", "zesty",
+}
+
+var nouns = []string{
+	"badger", "bat", "bear", "beaver", "bison", "camel", "cat", "cobra",
+	"crane", "crow", "deer", "dingo", "dove", "eagle", "falcon", "ferret",
+	"finch", "fox", "gecko", "goat", "goose", "hare", "hawk", "heron",
+	"hippo", "ibis", "koala", "lemur", "lion", "llama", "lynx", "marten",
+	"mole", "moose", "mouse", "newt", "otter", "owl", "panda", "parrot",
+	"pelican", "puffin", "quail", "rabbit", "raven", "robin", "salmon", "seal",
+	"shark", "sloth", "snail", "spider", "squid", "stork", "swan", "tiger",
+	"toad", "trout", "turtle", "walrus", "whale", "wolf", "wombat", "zebra",
+}
+
+func buildWordsHash() string {
+	return adjectives[rand.Intn(len(adjectives))] + "-" + nouns[rand.Intn(len(nouns))]
+}
+
+func wordsIDCount() int {
+	return len(adjectives) * len(nouns)
+}
+
+// isWordsID reports whether id is an adjective-noun pair from the built-in lists.
+func isWordsID(id string) bool {
+	adjective, noun, ok := strings.Cut(id, "-")
+	return ok && slices.Contains(adjectives, adjective) && slices.Contains(nouns, noun)
+}
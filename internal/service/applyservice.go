@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HandleApply applies a stash like pop but keeps the local and remote stash branch.
+func HandleApply(sel StashSelector, opts ApplyOptions) error {
+	stashes, err := retrieveStashesToApply()
+	if err != nil {
+		return err
+	}
+
+	if len(stashes) == 0 {
+		return errors.New("no stashes found to apply")
+	}
+	if stashes, err = scopeSelection(stashes, sel, opts.Scope); err != nil {
+		return err
+	}
+	if len(stashes) == 0 {
+		return errors.New("no stashes found to apply in scope; use --all to include everyone's stashes")
+	}
+
+	stash, err := resolveStash(stashes, sel)
+	if err != nil {
+		return err
+	}
+	if err := applyStash(stash, opts); err != nil {
+		return err
+	}
+	fmt.Println("Applied stash from branch: " + stash.Branch)
+	return nil
+}
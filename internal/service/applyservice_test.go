@@ -0,0 +1,91 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/config"
+	"8stash/internal/test"
+)
+
+func TestHandleApply_NoStashes_Error(t *testing.T) {
+	// Arrange
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	// Act
+	err := HandleApply(StashSelector{}, ApplyOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no stashes found to apply")
+}
+
+func TestHandleApply_SelectByNumber_KeepsStashBranch(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	branchName := config.BranchPrefix + "111"
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, branchName, "x.txt", "X", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"222", "y.txt", "Y", time.Now())
+	test.FetchAll(t, repo)
+
+	// Act
+	err = HandleApply(StashSelector{Query: "111"}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(filepath.Join(localPath, "x.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "X", string(b))
+
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	var onRemote bool
+	for _, r := range refs {
+		if r.Name().String() == "refs/heads/"+branchName {
+			onRemote = true
+		}
+	}
+	assert.True(t, onRemote, "applied stash should remain on the remote")
+
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(branchName), true)
+	assert.NoError(t, err, "applied stash should remain as a local branch")
+}
+
+func TestHandleApply_UnknownNumber_Error(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"111", "x.txt", "X", time.Now())
+	test.FetchAll(t, repo)
+
+	// Act
+	err = HandleApply(StashSelector{Query: "999"}, ApplyOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no stash matches 999")
+}
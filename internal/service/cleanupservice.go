@@ -5,20 +5,25 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"8stash/internal/config"
 	"8stash/internal/gitx"
 )
 
-func HandleCleanup() error {
+// HandleCleanup drops the stashes in scope that are older than the retention time.
+func HandleCleanup(scope Scope) error {
 	if err := gitx.UpdateRepository(); err != nil {
 		return fmt.Errorf("updating repository: %w", err)
 	}
 
-	stashes, _, _, err := gitx.GetBranchInformationMapsByPrefix(config.BranchPrefix)
+	stashes, err := gitx.ListStashes(config.BranchPrefix)
 	if err != nil {
 		return fmt.Errorf("get branches with prefix %s: %w", config.BranchPrefix, err)
 	}
+	if stashes, err = applyScope(stashes, scope); err != nil {
+		return err
+	}
 	fmt.Println("Cleaning up old stashes...")
 
 	if len(stashes) == 0 {
@@ -26,7 +31,7 @@ func HandleCleanup() error {
 		return nil
 	}
 
-	filtered := filterBranches(stashes, config.CleanUpTimeInDays)
+	filtered := filterBranches(stashes, config.CleanUpTimeInDays, time.Now())
 	if len(filtered) == 0 {
 		fmt.Println("No stashes found older than the cleanup time.")
 		return nil
@@ -39,10 +44,10 @@ func HandleCleanup() error {
 		return nil
 	}
 
-	for branch := range filtered {
-		fmt.Printf("Dropping stash branch: %s\n", branch)
-		if err := gitx.DeleteBranch(branch); err != nil {
-			return fmt.Errorf("drop branch %s: %w", branch, err)
+	for _, stash := range filtered {
+		fmt.Printf("Dropping stash branch: %s\n", stash.Branch)
+		if err := gitx.DeleteBranch(stash.Branch); err != nil {
+			return fmt.Errorf("drop branch %s: %w", stash.Branch, err)
 		}
 	}
 
@@ -50,27 +55,17 @@ func HandleCleanup() error {
 	return nil
 }
 
-func filterBranches(branches map[string]string, ageLimit int) map[string]string {
-	filtered := make(map[string]string)
-	for branch, age := range branches {
-		if ageInt, err := parseDayString(age); err != nil {
-			continue
-		} else if ageInt >= ageLimit {
-			filtered[branch] = age
+func filterBranches(stashes []gitx.Stash, ageLimit int, now time.Time) []gitx.Stash {
+	limit := time.Duration(ageLimit) * 24 * time.Hour
+	var filtered []gitx.Stash
+	for _, stash := range stashes {
+		if now.Sub(stash.Time) >= limit {
+			filtered = append(filtered, stash)
 		}
 	}
 	return filtered
 }
 
-func parseDayString(dayString string) (int, error) {
-	var days int
-	_, err := fmt.Sscanf(dayString, "%d days ago", &days)
-	if err != nil {
-		return 0, fmt.Errorf("parsing day string %s: %w", dayString, err)
-	}
-	return days, nil
-}
-
 func awaitConfirmation() bool {
 	if config.SkipConfirmations {
 		return true
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"8stash/internal/config"
+	"8stash/internal/gitx"
 	"8stash/internal/test"
 )
 
@@ -25,7 +26,7 @@ func TestHandleCleanup_NoStashes_Succeeds(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandleCleanup()
+	err = HandleCleanup(Scope{})
 
 	// Assert
 	require.NoError(t, err)
@@ -48,7 +49,7 @@ func TestHandleCleanup_NoOldStashes_NoDeletion(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandleCleanup()
+	err = HandleCleanup(Scope{})
 
 	// Assert
 	require.NoError(t, err)
@@ -98,7 +99,7 @@ func TestHandleCleanup_DeletesOnlyOldStashes(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandleCleanup()
+	err = HandleCleanup(Scope{})
 
 	// Assert
 	require.NoError(t, err)
@@ -125,6 +126,48 @@ func TestHandleCleanup_DeletesOnlyOldStashes(t *testing.T) {
 	assert.True(t, hasNew1, "new1 should remain")
 }
 
+func TestHandleCleanup_Template_KeepsOrdinaryBranchesBelowPrefix(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	origTemplate, origPrefix := config.NamingTemplate, config.BranchPrefix
+	config.SkipConfirmations = true
+	t.Cleanup(func() {
+		config.NamingTemplate, config.BranchPrefix = origTemplate, origPrefix
+		config.SkipConfirmations = false
+	})
+	config.NamingTemplate = "wip/{user}/{id}"
+	config.BranchPrefix = config.TemplatePrefix(config.NamingTemplate)
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	oldWhen := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "wip/alice/real-feature", "f.txt", "F", oldWhen)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, "wip/alice/1234", "s.txt", "S", oldWhen)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}))
+	test.FetchAll(t, repo)
+
+	// Act
+	err = HandleCleanup(Scope{})
+
+	// Assert
+	require.NoError(t, err)
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	names := map[string]bool{}
+	for _, r := range refs {
+		names[r.Name().String()] = true
+	}
+	assert.True(t, names["refs/heads/wip/alice/real-feature"], "an ordinary branch is not a stash")
+	assert.False(t, names["refs/heads/wip/alice/1234"], "the old stash is cleaned up")
+	_, err = repo.Reference(plumbing.NewBranchReferenceName("wip/alice/real-feature"), false)
+	assert.NoError(t, err, "the local branch is kept as well")
+}
+
 func TestHandleCleanup_DeleteOldCurrentBranch_ReturnsError(t *testing.T) {
 	// Arrange
 	localPath, cleanup := test.SetupTestRepo(t)
@@ -148,7 +191,7 @@ func TestHandleCleanup_DeleteOldCurrentBranch_ReturnsError(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandleCleanup()
+	err = HandleCleanup(Scope{})
 
 	// Assert
 	require.Error(t, err)
@@ -156,24 +199,21 @@ func TestHandleCleanup_DeleteOldCurrentBranch_ReturnsError(t *testing.T) {
 	assert.ErrorContains(t, err, "cannot delete current branch")
 }
 
-func TestFilterBranches_FiltersOnlyDaysAgoAndOlderOrEqual(t *testing.T) {
-	branches := map[string]string{
-		"b1": "30 days ago", // keep (== limit)
-		"b2": "29 days ago", // drop (< limit)
-		"b3": "45 days ago", // keep (> limit)
-		"b4": "1 day ago",   // drop (not 'days')
-		"b5": "3 hours ago", // drop (not 'days')
-		"b6": "invalid",     // drop (parse error)
+func TestFilterBranches_FiltersOnlyOlderOrEqualToLimit(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	stashes := []gitx.Stash{
+		{Branch: "b1", Time: now.Add(-30 * day)},             // keep (== limit)
+		{Branch: "b2", Time: now.Add(-30*day + time.Minute)}, // drop (just under limit)
+		{Branch: "b3", Time: now.Add(-45 * day)},             // keep (> limit)
+		{Branch: "b4", Time: now.Add(-1 * day)},              // drop
+		{Branch: "b5", Time: now.Add(-3 * time.Hour)},        // drop
 	}
-	out := filterBranches(branches, config.CleanUpTimeInDays)
+	out := filterBranches(stashes, config.CleanUpTimeInDays, now)
 
 	require.Len(t, out, 2)
-	assert.Equal(t, "30 days ago", out["b1"])
-	assert.Equal(t, "45 days ago", out["b3"])
-	assert.NotContains(t, out, "b2")
-	assert.NotContains(t, out, "b4")
-	assert.NotContains(t, out, "b5")
-	assert.NotContains(t, out, "b6")
+	assert.Equal(t, "b1", out[0].Branch)
+	assert.Equal(t, "b3", out[1].Branch)
 }
 
 func TestHandleCleanup_WithConfirmation_DeletesBranch(t *testing.T) {
@@ -202,7 +242,7 @@ func TestHandleCleanup_WithConfirmation_DeletesBranch(t *testing.T) {
     test.FetchAll(t, repo)
 
     // Act
-    err = HandleCleanup()
+    err = HandleCleanup(Scope{})
 
     // Assert
     require.NoError(t, err)
@@ -247,7 +287,7 @@ func TestHandleCleanup_WithAbort_DoesNotDeleteBranch(t *testing.T) {
     test.FetchAll(t, repo)
 
     // Act
-    err = HandleCleanup()
+    err = HandleCleanup(Scope{})
 
     // Assert
     require.NoError(t, err, "HandleCleanup should not error on abort")
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"8stash/internal/config"
+)
+
+// HandleConfigShow prints the effective value of every config key as key=value. With
+// showOrigin each line starts with the layer the value came from, like git config --show-origin.
+func HandleConfigShow(showOrigin bool) {
+	for _, s := range config.Settings() {
+		if showOrigin {
+			fmt.Printf("%s\t%s=%s\n", s.Origin, s.Key, s.Value)
+			continue
+		}
+		fmt.Printf("%s=%s\n", s.Key, s.Value)
+	}
+}
+
+// HandleConfigGet prints the effective value of one config key.
+func HandleConfigGet(key string) error {
+	for _, s := range config.Settings() {
+		if s.Key == key {
+			fmt.Println(s.Value)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown config key %q; run 8stash config to list all keys", key)
+}
+
+// HandleConfigSet writes a key to .8stash.yaml in the repository, or to the user config.
+func HandleConfigSet(key, value string, user bool) error {
+	path, err := configFilePath(user)
+	if err != nil {
+		return err
+	}
+	if err := config.SetFileKey(path, key, value); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s=%s in %s\n", key, value, path)
+	printRepoConfigHint(user)
+	return nil
+}
+
+// HandleConfigInit writes a commented config file with every key at its default.
+func HandleConfigInit(user, force bool) error {
+	path, err := configFilePath(user)
+	if err != nil {
+		return err
+	}
+	if err := config.WriteDefaultFile(path, force); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", path)
+	printRepoConfigHint(user)
+	return nil
+}
+
+// HandleConfigValidate checks every configuration layer and prints each problem with
+// the file and line or the variable it came from.
+func HandleConfigValidate(flags map[string]string) error {
+	problems, err := config.Validate(flags)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d config problem(s)", len(problems))
+	}
+	fmt.Println("Config is valid.")
+	return nil
+}
+
+// printRepoConfigHint tells that an uncommitted repository config file is kept out of
+// stashes and only applies to this clone.
+func printRepoConfigHint(user bool) {
+	if user {
+		return
+	}
+	fmt.Printf("Push leaves %s in place while it is untracked; commit it to share the settings.\n", config.ConfigName)
+}
+
+func configFilePath(user bool) (string, error) {
+	if !user {
+		return config.RepoConfigPath(), nil
+	}
+	if path := config.UserConfigPath(); path != "" {
+		return path, nil
+	}
+	return "", errors.New("cannot find the user config directory; set XDG_CONFIG_HOME")
+}
@@ -1,13 +1,62 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+
 	"8stash/internal/config"
 	"8stash/internal/gitx"
+	"8stash/internal/naming"
 )
 
-func HandleDrop(stashNr string) error {
-	if err := gitx.DeleteBranch(config.BranchPrefix + stashNr); err != nil {
+func HandleDrop(sel StashSelector) error {
+	if sel.IsEmpty() {
+		return errors.New("drop needs a stash id or selector")
+	}
+	stashes, err := Retrieve8stashList()
+	if err != nil {
+		return err
+	}
+
+	var branchName string
+	stash, err := resolveStash(stashes, sel)
+	switch {
+	case err == nil:
+		fmt.Printf("Dropping stash %s (%s, %s)\n", stash.ID, stash.AuthorName, stash.Message)
+		branchName = stash.Branch
+	case !isPlainID(sel):
+		return err
+	default:
+		// a plain id that is not on the remote may still exist as a local branch
+		local, found, localErr := localStashBranch(sel.Query)
+		if localErr != nil {
+			return localErr
+		}
+		if !found {
+			return err
+		}
+		branchName = local
+	}
+	if err := gitx.DeleteBranch(branchName); err != nil {
 		return err
 	}
 	return nil
 }
+
+// localStashBranch returns the local branch a stash id or branch name refers to.
+func localStashBranch(query string) (string, bool, error) {
+	names, err := gitx.StashNamesInUse(config.BranchPrefix)
+	if err != nil {
+		return "", false, err
+	}
+	for _, name := range names {
+		if name == query || naming.StashID(name, config.BranchPrefix) == query {
+			return name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func isPlainID(sel StashSelector) bool {
+	return sel.Author == "" && sel.Grep == "" && sel.Query != SelectLatest && sel.Query != SelectMine
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -28,7 +29,7 @@ func TestHandleDrop_Succeeds(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandleDrop(branchToDrop)
+	err = HandleDrop(StashSelector{Query: branchToDrop})
 
 	// Assert
 	require.NoError(t, err)
@@ -49,7 +50,7 @@ func TestHandleDrop_Succeeds(t *testing.T) {
 	assert.False(t, found, "dropped stash branch should not exist on remote")
 }
 
-func TestHandleDrop_BranchNotFound_NoError(t *testing.T) {
+func TestHandleDrop_UnknownID_ReturnsError(t *testing.T) {
 	// Arrange
 	localPath, cleanup := test.SetupTestRepo(t)
 	defer cleanup()
@@ -58,8 +59,28 @@ func TestHandleDrop_BranchNotFound_NoError(t *testing.T) {
 	require.NoError(t, err)
 
 	// Act
-	err = HandleDrop("nonexistent")
+	err = HandleDrop(StashSelector{Query: "nonexistent"})
+
+	// Assert
+	assert.ErrorContains(t, err, "nonexistent")
+}
+
+func TestHandleDrop_LocalWordsStashByBranchName_DeletesBranch(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	branch := plumbing.NewBranchReferenceName(config.BranchPrefix + "brave-otter")
+	require.NoError(t, repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())))
+
+	// Act
+	err = HandleDrop(StashSelector{Query: config.BranchPrefix + "brave-otter"})
 
 	// Assert
 	require.NoError(t, err)
+	_, err = repo.Reference(branch, false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
 }
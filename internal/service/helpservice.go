@@ -10,25 +10,64 @@ func PrintHelp() {
 	fmt.Println(spacer)
 
 	fmt.Println("Usage:")
-	fmt.Println("  8stash [command] [arguments]")
+	fmt.Println("  8stash [--remote name] [command] [arguments]")
+	fmt.Println()
+	fmt.Printf(formatString, "--remote <name>", "Use this remote for stashes instead of the branch's tracking remote.")
 	fmt.Println()
 
 	fmt.Println("Available Commands:")
 	fmt.Printf(formatString, "push [-m message]", "Save current work-in-progress to a new stash branch (default command).")
 	fmt.Printf(formatString, "", "Use -m to add a descriptive message to your stash.")
-	fmt.Printf(formatString, "pop <number?>", "Apply a stash, commit, and delete the remote stash branch.")
-	fmt.Printf(formatString, "list", "List all available 8stash branches with messages, authors, and timestamps.")
-	fmt.Printf(formatString, "drop <number>", "Delete a specific remote stash branch.")
+	fmt.Printf(formatString, "push [-m message] -- <path>...", "Stash only changes matching the given paths, directories or globs.")
+	fmt.Printf(formatString, "push --staged", "Stash only the staged changes; unstaged edits stay in place.")
+	fmt.Printf(formatString, "push -k, --keep-index", "Stash everything but keep the staged changes locally.")
+	fmt.Printf(formatString, "push --keep", "Share a snapshot of your changes and keep working on them locally.")
+	fmt.Printf(formatString, "push --with-commits", "Also hand off local commits that are not on the upstream branch.")
+	fmt.Printf(formatString, "push --retry", "Publish stashes whose push failed; your working tree is not touched.")
+	fmt.Printf(formatString, "pop <stash?>", "Apply a stash, commit, and delete the remote stash branch.")
+	fmt.Printf(formatString, "apply <stash?>", "Apply a stash like pop but keep the stash branch for others.")
+	fmt.Printf(formatString, "", "Local commits in a stash are replayed; --squash makes them unstaged changes.")
+	fmt.Printf(formatString, "", "On a diverged branch each commit is cherry-picked onto HEAD.")
+	fmt.Printf(formatString, "list [-f format]", "List all available 8stash branches with messages, authors, and timestamps.")
+	fmt.Printf(formatString, "", "Use -f json or -f porcelain for stable, versioned output for scripts.")
+	fmt.Printf(formatString, "", "Use --offline to list the cached stashes without contacting the remote.")
+	fmt.Printf(formatString, "", "Use --mine or --author <pattern> to list only some people's stashes.")
+	fmt.Printf(formatString, "show <stash> [--stat]", "Show the changes in a stash without applying it.")
+	fmt.Printf(formatString, "", "Use --stat for a summary or --name-only for just the file names.")
+	fmt.Printf(formatString, "drop <stash>", "Delete a specific remote stash branch.")
 	fmt.Printf(formatString, "cleanup [-d days] [-y]", "Delete old stashes. -d overrides retention, -y skips confirmation.")
+	fmt.Printf(formatString, "", "list, pop, apply and cleanup take --mine and --all to override the scope setting.")
+	fmt.Printf(formatString, "migrate", "Move branch based stashes to the refs/<prefix> namespace.")
+	fmt.Printf(formatString, "config [--show-origin]", "Show the effective configuration and, with --show-origin, where each value comes from.")
+	fmt.Printf(formatString, "config get <key>", "Print the effective value of one key, e.g. naming.hash_type.")
+	fmt.Printf(formatString, "config set [--user] <key> <value>", "Set a key in .8stash.yaml, or with --user in ~/.config/8stash/config.yaml.")
+	fmt.Printf(formatString, "config init [--user] [--force]", "Write a commented config file with every key at its default.")
+	fmt.Printf(formatString, "config validate", "Report every problem in every config source with its file and line.")
 	fmt.Printf(formatString, "help", "Show this help message.")
 	fmt.Println(spacer)
 
+	fmt.Println("Selecting stashes (pop, apply, show, drop):")
+	fmt.Printf(formatString, "<id>", "The stash id as shown by list, numeric or UUID.")
+	fmt.Printf(formatString, "@latest", "The most recent stash.")
+	fmt.Printf(formatString, "@mine", "Your most recent stash, matched by git user.email.")
+	fmt.Printf(formatString, "--author <name>", "Stashes whose author name or email contains <name>.")
+	fmt.Printf(formatString, "--grep <regex>", "Stashes whose message matches <regex>.")
+	fmt.Printf(formatString, "", "Selectors can be combined; ambiguous matches list the candidates.")
+	fmt.Println(spacer)
+
 	fmt.Println("Configuration:")
-	fmt.Println("  8Stash can be configured via a `.8stash.yaml` file in your repository root.")
+	fmt.Println("  8Stash reads ~/.config/8stash/config.yaml, then `.8stash.yaml` in your repository root,")
+	fmt.Println("  then eightstash.* git config keys, EIGHTSTASH_* environment variables and --remote; later ones win.")
 	fmt.Println("  Key options include:")
 	fmt.Println("    - branch_prefix: Customize the prefix for stash branches (e.g., 'wip/').")
 	fmt.Println("    - retention_days: Set the age for the 'cleanup' command.")
-	fmt.Println("    - naming: Configure stash ID format (e.g., numeric length or UUID).")
+	fmt.Println("    - remote: The remote holding the stashes, e.g. 'upstream' in a fork.")
+	fmt.Println("    - storage: Store stashes as 'branches' (default) or as 'refs' outside the branch list.")
+	fmt.Println("    - scope: 'mine' makes list, pop and cleanup show only your stashes by default.")
+	fmt.Println("    - auth: HTTPS username, token variable and credential helper use (tokens via EIGHTSTASH_TOKEN by default).")
+	fmt.Println("      SSH key file, user and known_hosts file (key also via EIGHTSTASH_SSH_KEY).")
+	fmt.Println("    - naming: Configure stash ID format (numeric, uuid, words such as brave-otter, or sequential).")
+	fmt.Println("      naming.template lays out branch names, e.g. 'wip/{user}/{base}/{id}'.")
 	fmt.Println()
 	fmt.Println("  For more details on configuration, see the README.md file.")
 	fmt.Println(spacer)
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+
+	"8stash/internal/gitx"
+)
+
+// ListFormatVersion is bumped whenever a field of the json or porcelain output changes.
+const ListFormatVersion = 1
+
+const (
+	ListFormatTable     = "table"
+	ListFormatJSON      = "json"
+	ListFormatPorcelain = "porcelain"
+)
+
+type jsonStashList struct {
+	Version int         `json:"version"`
+	Stashes []jsonStash `json:"stashes"`
+}
+
+type jsonStash struct {
+	ID         string     `json:"id"`
+	Branch     string     `json:"branch"`
+	Commit     string     `json:"commit"`
+	Timestamp  string     `json:"timestamp"`
+	Author     jsonAuthor `json:"author"`
+	Message    string     `json:"message"`
+	BaseBranch string     `json:"base_branch"`
+	BaseCommit string     `json:"base_commit"`
+}
+
+type jsonAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func isValidListFormat(format string) bool {
+	switch format {
+	case ListFormatTable, ListFormatJSON, ListFormatPorcelain:
+		return true
+	}
+	return false
+}
+
+func writeStashesJSON(w io.Writer, stashes []gitx.Stash) error {
+	out := jsonStashList{Version: ListFormatVersion, Stashes: []jsonStash{}}
+	for _, s := range stashes {
+		out.Stashes = append(out.Stashes, jsonStash{
+			ID:         s.ID,
+			Branch:     s.Branch,
+			Commit:     s.Hash.String(),
+			Timestamp:  s.Time.UTC().Format(time.RFC3339),
+			Author:     jsonAuthor{Name: s.AuthorName, Email: s.AuthorEmail},
+			Message:    s.Message,
+			BaseBranch: s.BaseBranch,
+			BaseCommit: hashOrEmpty(s.BaseCommit),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeStashesPorcelain prints a version header followed by one tab separated line per stash:
+// id, branch, commit, timestamp, author name, author email, base branch, base commit, message.
+func writeStashesPorcelain(w io.Writer, stashes []gitx.Stash) error {
+	if _, err := fmt.Fprintf(w, "# 8stash porcelain v%d\n", ListFormatVersion); err != nil {
+		return err
+	}
+	for _, s := range stashes {
+		fields := []string{
+			s.ID,
+			s.Branch,
+			s.Hash.String(),
+			s.Time.UTC().Format(time.RFC3339),
+			s.AuthorName,
+			s.AuthorEmail,
+			s.BaseBranch,
+			hashOrEmpty(s.BaseCommit),
+			s.Message,
+		}
+		for i, f := range fields {
+			fields[i] = porcelainField(f)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// porcelainField keeps a value on a single line and free of field separators.
+func porcelainField(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+func hashOrEmpty(hash plumbing.Hash) string {
+	if hash.IsZero() {
+		return ""
+	}
+	return hash.String()
+}
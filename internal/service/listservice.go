@@ -2,36 +2,58 @@ package service
 
 import (
 	"fmt"
-	"sort"
+	"os"
+	"time"
 
 	"8stash/internal/config"
 	"8stash/internal/gitx"
 )
 
-func HandleList() error {
-	if err := gitx.UpdateRepository(); err != nil {
-		return err
+type ListOptions struct {
+	Format string
+	// Offline lists from the cached tracking refs without contacting the remote.
+	Offline bool
+	Scope   Scope
+}
+
+func HandleList(opts ListOptions) error {
+	if !isValidListFormat(opts.Format) {
+		return fmt.Errorf("unknown list format %q: use table, json or porcelain", opts.Format)
+	}
+	var stashes []gitx.Stash
+	var err error
+	if opts.Offline {
+		stashes, err = gitx.ListStashes(config.BranchPrefix)
+	} else {
+		stashes, err = Retrieve8stashList()
 	}
-	listOfStashes, listOfStashesWithAuthor, listOfStashesWithMessages, err := Retrieve8stashList()
 	if err != nil {
 		return err
 	}
-	printStashes(listOfStashes, listOfStashesWithAuthor, listOfStashesWithMessages)
+	if stashes, err = applyScope(stashes, opts.Scope); err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case ListFormatJSON:
+		return writeStashesJSON(os.Stdout, stashes)
+	case ListFormatPorcelain:
+		return writeStashesPorcelain(os.Stdout, stashes)
+	}
+	printStashes(stashes, time.Now())
 	return nil
 }
 
-func Retrieve8stashList() (map[string]string, map[string]string, map[string]string, error) {
-	if err := gitx.UpdateRepository(); err != nil {
-		return nil, nil, nil, err
-	}
-	mapOfListAndTime, mapOfListAndAuthor, mapOfListAndMessage, err := gitx.GetBranchInformationMapsByPrefix(config.BranchPrefix)
-	if err != nil {
-		return nil, nil, nil, err
+// Retrieve8stashList fetches the stash refs from the remote and lists them.
+// Unlike a pull it never touches the checked-out branch.
+func Retrieve8stashList() ([]gitx.Stash, error) {
+	if err := gitx.FetchStashes(config.BranchPrefix); err != nil {
+		return nil, err
 	}
-	return mapOfListAndTime, mapOfListAndAuthor, mapOfListAndMessage, nil
+	return gitx.ListStashes(config.BranchPrefix)
 }
 
-func printStashes(stashes map[string]string, stashesAndAuthors map[string]string, stashesAndMessages map[string]string) {
+func printStashes(stashes []gitx.Stash, now time.Time) {
 	if len(stashes) == 0 {
 		fmt.Println("No stashes found.")
 		return
@@ -39,27 +61,19 @@ func printStashes(stashes map[string]string, stashesAndAuthors map[string]string
 	fmt.Println("Available stashes:")
 	fmt.Println("-------------------------------------------------------------------")
 
-	var stashList []struct {
-		name    string
-		time    string
-		author  string
-		message string
+	for _, stash := range stashes {
+		fmt.Printf("%-30s - %-15s - %-15s | %s\n", stash.Branch, formatAge(now.Sub(stash.Time)), stash.AuthorName, stash.Message)
 	}
+}
 
-	for name, time := range stashes {
-		stashList = append(stashList, struct {
-			name    string
-			time    string
-			author  string
-			message string
-		}{name, time, stashesAndAuthors[name], stashesAndMessages[name]})
+func formatAge(age time.Duration) string {
+	days := int(age.Hours() / 24)
+	if days > 0 {
+		return fmt.Sprintf("%d days ago", days)
 	}
-
-	sort.Slice(stashList, func(i, j int) bool {
-		return stashList[i].name < stashList[j].name
-	})
-
-	for _, stash := range stashList {
-		fmt.Printf("%-30s - %-15s - %-15s | %s\n", stash.name, stash.time, stash.author, stash.message)
+	hours := int(age.Hours())
+	if hours > 0 {
+		return fmt.Sprintf("%dh ago", hours)
 	}
+	return fmt.Sprintf("%dmin ago", int(age.Minutes()))
 }
@@ -2,13 +2,16 @@ package service
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,7 +41,7 @@ func TestHandleList_PrintsStashesWithAuthorAndTime(t *testing.T) {
 	// Act
 	var actErr error
 	out := captureOutput(t, func() {
-		actErr = HandleList()
+		actErr = HandleList(ListOptions{Format: ListFormatTable})
 	})
 
 	// Assert
@@ -62,7 +65,7 @@ func TestHandleList_NoStashes(t *testing.T) {
 	// Act
 	var actErr error
 	out := captureOutput(t, func() {
-		actErr = HandleList()
+		actErr = HandleList(ListOptions{Format: ListFormatTable})
 	})
 
 	// Assert
@@ -88,7 +91,7 @@ func TestHandleList_SortsOutputByName(t *testing.T) {
 	// Act
 	var actErr error
 	out := captureOutput(t, func() {
-		actErr = HandleList()
+		actErr = HandleList(ListOptions{Format: ListFormatTable})
 	})
 
 	// Assert
@@ -101,6 +104,109 @@ func TestHandleList_SortsOutputByName(t *testing.T) {
 	assert.Contains(t, out, "stash "+config.BranchPrefix+"zebra", "Output should contain commit message for zebra")
 }
 
+func TestHandleList_JSONFormat_PrintsVersionedStashes(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	when := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	author := &object.Signature{Name: "Author One", Email: "one@example.com", When: when}
+	test.CreateAndPushStashBranchWithAuthor(t, repo, wt, localPath, config.BranchPrefix+"7", "one.txt", "1", author)
+	test.FetchAll(t, repo)
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleList(ListOptions{Format: ListFormatJSON})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	var got jsonStashList
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assert.Equal(t, ListFormatVersion, got.Version)
+	require.Len(t, got.Stashes, 1)
+	s := got.Stashes[0]
+	assert.Equal(t, "7", s.ID)
+	assert.Equal(t, config.BranchPrefix+"7", s.Branch)
+	assert.Equal(t, "2024-05-01T12:30:00Z", s.Timestamp)
+	assert.Equal(t, "Author One", s.Author.Name)
+	assert.Equal(t, "one@example.com", s.Author.Email)
+	assert.Equal(t, "stash "+config.BranchPrefix+"7", s.Message)
+	assert.Len(t, s.Commit, 40)
+	assert.Len(t, s.BaseCommit, 40)
+}
+
+func TestHandleList_JSONFormat_NoStashes_PrintsEmptyList(t *testing.T) {
+	// Arrange
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleList(ListOptions{Format: ListFormatJSON})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.JSONEq(t, `{"version": 1, "stashes": []}`, out)
+}
+
+func TestHandleList_PorcelainFormat_PrintsHeaderAndTabSeparatedLines(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	when := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	author := &object.Signature{Name: "Author One", Email: "one@example.com", When: when}
+	test.CreateAndPushStashBranchWithAuthor(t, repo, wt, localPath, config.BranchPrefix+"7", "one.txt", "1", author)
+	test.FetchAll(t, repo)
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleList(ListOptions{Format: ListFormatPorcelain})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "# 8stash porcelain v1", lines[0])
+	fields := strings.Split(lines[1], "\t")
+	require.Len(t, fields, 9)
+	assert.Equal(t, "7", fields[0])
+	assert.Equal(t, config.BranchPrefix+"7", fields[1])
+	assert.Equal(t, "2024-05-01T12:30:00Z", fields[3])
+	assert.Equal(t, "Author One", fields[4])
+	assert.Equal(t, "one@example.com", fields[5])
+	assert.Equal(t, "stash "+config.BranchPrefix+"7", fields[8])
+}
+
+func TestHandleList_UnknownFormat_Error(t *testing.T) {
+	// Arrange
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	// Act
+	err := HandleList(ListOptions{Format: "yaml"})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "unknown list format")
+}
+
 func captureOutput(t *testing.T, fn func()) string {
 	t.Helper()
 	old := os.Stdout
@@ -117,3 +223,89 @@ func captureOutput(t *testing.T, fn func()) string {
 	_, _ = io.Copy(&buf, r)
 	return buf.String()
 }
+
+func removeOnRemote(t *testing.T, repo *git.Repository, refName string) {
+	t.Helper()
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	remote, err := git.PlainOpen(cfg.Remotes["origin"].URLs[0])
+	require.NoError(t, err)
+	require.NoError(t, remote.Storer.RemoveReference(plumbing.ReferenceName(refName)))
+}
+
+func TestHandleList_DivergedCurrentBranch_ListsWithoutTouchingIt(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"one", "one.txt", "1", time.Now())
+
+	// main diverges: a local commit plus a different commit on the remote
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "remote.txt"), []byte("remote"), 0o644))
+	_, err = wt.Add("remote.txt")
+	require.NoError(t, err)
+	_, err = wt.Commit("remote commit", &git.CommitOptions{Author: &object.Signature{Name: "R", Email: "r@example.com", When: time.Now()}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Push(&git.PushOptions{RemoteName: "origin"}))
+	require.NoError(t, wt.Reset(&git.ResetOptions{Mode: git.HardReset, Commit: headParent(t, repo)}))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "local.txt"), []byte("local"), 0o644))
+	_, err = wt.Add("local.txt")
+	require.NoError(t, err)
+	localHead, err := wt.Commit("local commit", &git.CommitOptions{Author: &object.Signature{Name: "L", Email: "l@example.com", When: time.Now()}})
+	require.NoError(t, err)
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleList(ListOptions{Format: ListFormatTable})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Contains(t, out, "8stash/one")
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, localHead, head.Hash(), "list must not change the current branch")
+}
+
+func TestHandleList_PrunesStashesDeletedOnRemote_OfflineUsesCache(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"gone", "gone.txt", "g", time.Now())
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"kept", "kept.txt", "k", time.Now())
+	test.FetchAll(t, repo)
+	removeOnRemote(t, repo, "refs/heads/"+config.BranchPrefix+"gone")
+
+	// Act
+	offline := captureOutput(t, func() {
+		require.NoError(t, HandleList(ListOptions{Format: ListFormatTable, Offline: true}))
+	})
+	online := captureOutput(t, func() {
+		require.NoError(t, HandleList(ListOptions{Format: ListFormatTable}))
+	})
+
+	// Assert
+	assert.Contains(t, offline, "8stash/gone", "offline lists the cached refs")
+	assert.Contains(t, offline, "8stash/kept")
+	assert.NotContains(t, online, "8stash/gone", "deleted stash should be pruned")
+	assert.Contains(t, online, "8stash/kept")
+}
+
+func headParent(t *testing.T, repo *git.Repository) plumbing.Hash {
+	t.Helper()
+	head, err := repo.Head()
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	return commit.ParentHashes[0]
+}
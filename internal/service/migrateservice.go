@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+
+	"8stash/internal/config"
+	"8stash/internal/gitx"
+	"8stash/internal/validation"
+)
+
+// HandleMigrate moves branch based stashes into the ref namespace on the remote.
+func HandleMigrate() error {
+	if err := validation.IsGitRepository(); err != nil {
+		return err
+	}
+	if err := gitx.UpdateRepository(); err != nil {
+		return err
+	}
+
+	migrated, err := gitx.MigrateBranchStashes(config.BranchPrefix)
+	for _, m := range migrated {
+		fmt.Printf("Migrated stash %s to %s\n", m.Name, m.Ref)
+	}
+	if err != nil {
+		return err
+	}
+	if len(migrated) == 0 {
+		fmt.Println("No branch based stashes found to migrate.")
+		return nil
+	}
+	if config.Storage != config.StorageRefs {
+		fmt.Printf("Set 'storage: %s' in %s so 8stash uses the migrated stashes.\n", config.StorageRefs, config.ConfigName)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/config"
+	"8stash/internal/test"
+)
+
+func TestHandleMigrate_PrintsDestinationRefs(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"5", "a.txt", "A", time.Now())
+	test.FetchAll(t, repo)
+	require.Equal(t, config.StorageBranches, config.Storage)
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleMigrate()
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Contains(t, out, "Migrated stash "+config.BranchPrefix+"5 to refs/"+config.BranchPrefix+"5\n")
+	assert.Contains(t, out, "storage: "+config.StorageRefs)
+}
@@ -9,16 +9,16 @@ import (
 	"8stash/internal/validation"
 )
 
-func HandlePop(stashNumber string) error {
-	if err := validation.IsGitRepository(); err != nil {
-		return err
-	}
-
-	if err := gitx.UpdateRepository(); err != nil {
-		return err
-	}
+// ApplyOptions controls how pop and apply bring a stash into the current branch.
+type ApplyOptions struct {
+	// Squash applies the local commits carried by a stash as unstaged changes
+	// instead of replaying them onto the current branch.
+	Squash bool
+	Scope  Scope
+}
 
-	stashes, _, _, err := Retrieve8stashList()
+func HandlePop(sel StashSelector, opts ApplyOptions) error {
+	stashes, err := retrieveStashesToApply()
 	if err != nil {
 		return err
 	}
@@ -26,55 +26,54 @@ func HandlePop(stashNumber string) error {
 	if len(stashes) == 0 {
 		return errors.New("no pops found")
 	}
+	if stashes, err = scopeSelection(stashes, sel, opts.Scope); err != nil {
+		return err
+	}
+	if len(stashes) == 0 {
+		return errors.New("no pops found in scope; use --all to include everyone's stashes")
+	}
 
-	if len(stashes) > 1 {
-		if stashNumber == "0" {
-			return errors.New("multiple pops found an no stash number given")
-		}
-		if err := popStash(stashNumber, stashes); err != nil {
-			return err
+	stash, err := resolveStash(stashes, sel)
+	if err != nil {
+		if sel.IsEmpty() {
+			return fmt.Errorf("multiple pops found an no stash number given; %w", err)
 		}
-		return nil
+		return err
 	}
-
-	if err := popStash(stashNumber, stashes); err != nil {
+	if err := applyStash(stash, opts); err != nil {
+		return err
+	}
+	fmt.Println("Popped stash from branch: " + stash.Branch)
+	if err := gitx.DeleteBranch(stash.Branch); err != nil {
+		fmt.Println("Warning: failed to delete stash branch: " + stash.Branch)
 		return err
 	}
 	return nil
 }
 
-func popStash(stashNumber string, stashes map[string]string) error {
-	if stashNumber == "0" {
-		if len(stashes) > 1 {
-			return errors.New("multiple stashes found; please specify which one to pop")
-		}
-		for branchName := range stashes {
-			return applyAndRemoveStash(branchName)
-		}
-		fmt.Println("No stashes to pop.")
-		return nil
+func retrieveStashesToApply() ([]gitx.Stash, error) {
+	if err := validation.IsGitRepository(); err != nil {
+		return nil, err
 	}
-
-	branchName := config.BranchPrefix + stashNumber
-	return applyAndRemoveStash(branchName)
+	// Applying needs an up to date branch, listing alone does not. The update also
+	// fetches the stashes, so they are listed without fetching again.
+	if err := gitx.UpdateRepository(); err != nil {
+		return nil, err
+	}
+	return gitx.ListStashes(config.BranchPrefix)
 }
 
-func applyAndRemoveStash(branchName string) error {
-	err := gitx.MergeStashIntoCurrentBranch(branchName)
-	if err != nil {
-		if errors.Is(err, gitx.ErrNonFastForward) {
-			fmt.Println("Branches have diverged, attempting a three-way merge...")
-			if mergeErr := gitx.ApplyDivergedMerge(branchName); mergeErr != nil {
-				return mergeErr
-			}
-		} else {
-			return err
-		}
+func applyStash(stash gitx.Stash, opts ApplyOptions) error {
+	if stash.WithCommits && !opts.Squash {
+		return gitx.ReplayStashIntoCurrentBranch(stash.Branch)
 	}
-	fmt.Println("Popped stash from branch: " + branchName)
-	if err := gitx.DeleteBranch(branchName); err != nil {
-		fmt.Println("Warning: failed to delete stash branch: " + branchName)
+	err := gitx.MergeStashIntoCurrentBranch(stash.Branch)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gitx.ErrNonFastForward) {
 		return err
 	}
-	return nil
+	fmt.Println("Branches have diverged, attempting a three-way merge...")
+	return gitx.ApplyDivergedMerge(stash.Branch)
 }
@@ -7,12 +7,14 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"8stash/internal/test"
 	"8stash/internal/config"
+	"8stash/internal/gitx"
 )
 
 func TestHandlePop_NoStashes_Error(t *testing.T) {
@@ -25,7 +27,7 @@ func TestHandlePop_NoStashes_Error(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("0")
+	err = HandlePop(StashSelector{}, ApplyOptions{})
 
 	// Assert
 	require.Error(t, err)
@@ -47,7 +49,7 @@ func TestHandlePop_Multiple_ZeroNumber_Error(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("0")
+	err = HandlePop(StashSelector{}, ApplyOptions{})
 
 	// Assert
 	require.Error(t, err)
@@ -72,7 +74,7 @@ func TestHandlePop_Single_ZeroNumber_Succeeds(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("0")
+	err = HandlePop(StashSelector{}, ApplyOptions{})
 
 	// Assert
 	require.NoError(t, err)
@@ -111,7 +113,7 @@ func TestHandlePop_SelectByNumber_Succeeds(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("111")
+	err = HandlePop(StashSelector{Query: "111"}, ApplyOptions{})
 
 	// Assert we do not assert that HandlePop has no Error because this is supposed to happen when no brach is found after pop
 	remote, err := repo.Remote("origin")
@@ -166,7 +168,7 @@ func TestHandlePop_DivergedBranches_TriggersMerge(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("diverge")
+	err = HandlePop(StashSelector{Query: "diverge"}, ApplyOptions{})
 
 	// Assert - should succeed with no error
 	require.NoError(t, err)
@@ -207,9 +209,212 @@ func TestHandlePop_DivergedBranches_MergeConflict(t *testing.T) {
 	test.FetchAll(t, repo)
 
 	// Act
-	err = HandlePop("conflict")
+	err = HandlePop(StashSelector{Query: "conflict"}, ApplyOptions{})
 
 	// Assert
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "CONFLICT")
 }
+
+func TestHandlePop_RefStorage_AppliesAndRemovesStashRef(t *testing.T) {
+	// Arrange
+	origStorage := config.Storage
+	config.Storage = config.StorageRefs
+	t.Cleanup(func() { config.Storage = origStorage })
+
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+	stashName, err := HandlePush(PushOptions{})
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(localPath, "wip.txt"))
+	require.True(t, os.IsNotExist(err))
+
+	// Act
+	err = HandlePop(StashSelector{Query: SelectLatest}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(localPath, "wip.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "wip", string(b))
+
+	remote, err := repo.Remote("origin")
+	require.NoError(t, err)
+	refs, err := remote.List(&git.ListOptions{})
+	require.NoError(t, err)
+	for _, r := range refs {
+		assert.NotEqual(t, "refs/"+stashName, r.Name().String(), "popped stash ref should be deleted")
+	}
+}
+
+func TestHandlePop_DetachedHead_AppliesStash(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"solo", "solo.txt", "hello", time.Now())
+	test.FetchAll(t, repo)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Hash: head.Hash()}))
+
+	// Act
+	err = HandlePop(StashSelector{}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(localPath, "solo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	after, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "HEAD", after.Name().String(), "HEAD should stay detached")
+	assert.Equal(t, head.Hash(), after.Hash())
+}
+
+// resetToUpstream moves main back to the upstream commit, like a teammate who has
+// not seen the local commits yet.
+func resetToUpstream(t *testing.T, repo *git.Repository, wt *git.Worktree, upstream plumbing.Hash) {
+	t.Helper()
+	require.NoError(t, wt.Reset(&git.ResetOptions{Mode: git.HardReset, Commit: upstream}))
+	status, err := wt.Status()
+	require.NoError(t, err)
+	require.True(t, status.IsClean())
+}
+
+func TestHandlePop_WithCommits_ReplaysCommits(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	stash, localCommit, upstream := pushStashWithLocalCommit(t, repo, wt, localPath)
+	resetToUpstream(t, repo, wt, upstream)
+
+	// Act
+	err = HandlePop(StashSelector{Query: stash.ID}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+	assert.Equal(t, localCommit, head.Hash(), "the local commit is replayed onto main")
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, git.Untracked, status.File("wip.txt").Worktree)
+	assert.NotContains(t, status, "local.txt", "the committed file is clean")
+}
+
+// divergeFromUpstream moves main back to the upstream commit and adds a commit of the
+// recipient's own, so the branch has diverged from the stash.
+func divergeFromUpstream(t *testing.T, repo *git.Repository, wt *git.Worktree, localPath string, upstream plumbing.Hash, fileName, content string) plumbing.Hash {
+	t.Helper()
+	resetToUpstream(t, repo, wt, upstream)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, fileName), []byte(content), 0o644))
+	_, err := wt.Add(fileName)
+	require.NoError(t, err)
+	hash, err := wt.Commit("recipient work", &git.CommitOptions{
+		Author: &object.Signature{Name: "R", Email: "r@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash
+}
+
+func TestHandlePop_WithCommits_DivergedBranch_CherryPicksCommits(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	stash, localCommit, upstream := pushStashWithLocalCommit(t, repo, wt, localPath)
+	recipient := divergeFromUpstream(t, repo, wt, localPath, upstream, "main.txt", "main content")
+
+	// Act
+	err = HandlePop(StashSelector{Query: stash.ID}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", head.Name().String())
+	assert.NotEqual(t, localCommit, head.Hash(), "the local commit is rewritten onto main")
+	replayed, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{recipient}, replayed.ParentHashes)
+	assert.Equal(t, "local work", replayed.Message)
+	assert.Equal(t, "T", replayed.Author.Name, "the original author is kept")
+
+	b, err := os.ReadFile(filepath.Join(localPath, "local.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "committed", string(b))
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, git.Untracked, status.File("wip.txt").Worktree)
+	assert.NotContains(t, status, "local.txt", "the committed file is clean")
+	assert.NotContains(t, status, "main.txt", "the recipient's commit is kept")
+}
+
+func TestHandlePop_WithCommits_DivergedBranch_ConflictStopsReplay(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	stash, _, upstream := pushStashWithLocalCommit(t, repo, wt, localPath)
+	recipient := divergeFromUpstream(t, repo, wt, localPath, upstream, "local.txt", "recipient")
+
+	// Act
+	err = HandlePop(StashSelector{Query: stash.ID}, ApplyOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "local work")
+	assert.Contains(t, err.Error(), "CONFLICT (add/add): Merge conflict in local.txt")
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, recipient, head.Hash(), "no commit is made for the conflicting change")
+	stashes, err := gitx.ListStashes(config.BranchPrefix)
+	require.NoError(t, err)
+	assert.Len(t, stashes, 1, "the stash is kept when the pop fails")
+}
+
+func TestHandlePop_WithCommits_SquashLeavesEverythingUnstaged(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	stash, _, upstream := pushStashWithLocalCommit(t, repo, wt, localPath)
+	resetToUpstream(t, repo, wt, upstream)
+
+	// Act
+	err = HandlePop(StashSelector{Query: stash.ID}, ApplyOptions{Squash: true})
+
+	// Assert
+	require.NoError(t, err)
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, upstream, head.Hash(), "no commits are added to main")
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, git.Untracked, status.File("wip.txt").Worktree)
+	assert.Equal(t, git.Untracked, status.File("local.txt").Worktree)
+}
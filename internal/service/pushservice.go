@@ -1,24 +1,97 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"8stash/internal/config"
 	"8stash/internal/gitx"
 	"8stash/internal/naming"
+	"8stash/internal/validation"
 )
 
-func HandlePush(commitMessage string) (string, error) {
-	if err := gitx.PrepareRepository(); err != nil {
+// maxPushAttempts bounds how often push picks a new id after losing a race for one.
+const maxPushAttempts = 5
+
+// stashNamesInUse is the local view of taken stash names; tests replace it to lose a race.
+var stashNamesInUse = gitx.StashNamesInUse
+
+type PushOptions struct {
+	Message    string
+	Pathspecs  []string
+	StagedOnly bool
+	KeepIndex  bool
+	Keep       bool
+	// WithCommits also hands off local commits that are not on the upstream branch.
+	WithCommits bool
+}
+
+func HandlePush(opts PushOptions) (string, error) {
+	prepare := gitx.PrepareRepository
+	if opts.WithCommits {
+		prepare = gitx.PrepareRepositoryWithCommits
+	}
+	if err := prepare(); err != nil {
 		return "", err
 	}
 
-	stashName, err := naming.BuildStashHash()
+	stashOpts := gitx.StashOptions{
+		Message:     opts.Message,
+		Pathspecs:   opts.Pathspecs,
+		StagedOnly:  opts.StagedOnly,
+		KeepIndex:   opts.KeepIndex,
+		Keep:        opts.Keep,
+		WithCommits: opts.WithCommits,
+	}
+	fields, err := stashNameFields()
 	if err != nil {
 		return "", err
 	}
+	for attempt := 1; ; attempt++ {
+		inUse, err := stashNamesInUse(config.BranchPrefix)
+		if err != nil {
+			return "", err
+		}
+		stashName, err := naming.BuildStashHash(inUse, fields)
+		if err != nil {
+			return "", err
+		}
+		err = gitx.StashChangesToNewBranch(stashName, stashOpts)
+		// Someone pushed a stash with the same id since the last fetch: fetch it and pick the next id.
+		if errors.Is(err, gitx.ErrStashNameTaken) && attempt < maxPushAttempts {
+			if err := gitx.FetchStashes(config.BranchPrefix); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if errors.Is(err, gitx.ErrStashPending) {
+			return "", fmt.Errorf("%w; your changes are untouched, run '8stash push --retry' to publish the stash", err)
+		}
+		if err != nil {
+			return "", err
+		}
+		return stashName, nil
+	}
+}
 
-	err = gitx.StashChangesToNewBranch(stashName, commitMessage)
+// stashNameFields collects what naming.template may put into a stash branch name.
+func stashNameFields() (naming.Fields, error) {
+	name, email, err := gitx.CurrentUser()
 	if err != nil {
-		return "", err
+		return naming.Fields{}, err
 	}
+	branch, err := gitx.CurrentBranch()
+	if err != nil {
+		return naming.Fields{}, err
+	}
+	return naming.Fields{User: name, Email: email, Base: branch, Date: time.Now()}, nil
+}
 
-	return stashName, nil
+// HandlePushRetry publishes stashes whose push failed earlier and returns their branch names.
+func HandlePushRetry() ([]string, error) {
+	if err := validation.IsGitRepository(); err != nil {
+		return nil, err
+	}
+	return gitx.PublishPendingStashes()
 }
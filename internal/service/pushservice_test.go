@@ -5,12 +5,16 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"8stash/internal/config"
+	"8stash/internal/gitx"
 	"8stash/internal/test"
 )
 
@@ -28,7 +32,7 @@ func TestHandlePush_Succeeds(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("work in progress"), 0o644))
 
 	// Act
-	stashName, err := HandlePush("")
+	stashName, err := HandlePush(PushOptions{})
 
 	// Assert
 	require.NoError(t, err)
@@ -59,4 +63,160 @@ func TestHandlePush_Succeeds(t *testing.T) {
 	status, err := wt.Status()
 	require.NoError(t, err)
 	assert.True(t, status.IsClean())
-}
\ No newline at end of file
+}
+func TestHandlePush_Sequential_AllocatesNextFreeID(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	origType := config.NamingHashType
+	t.Cleanup(func() { config.NamingHashType = origType })
+	config.NamingHashType = config.HashSequential
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"9", "nine.txt", "9", time.Now())
+
+	// Act
+	var names []string
+	for _, file := range []string{"a.txt", "b.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(localPath, file), []byte("wip"), 0o644))
+		name, err := HandlePush(PushOptions{})
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+
+	// Assert
+	assert.Equal(t, []string{config.BranchPrefix + "10", config.BranchPrefix + "11"}, names)
+}
+
+func TestHandlePush_NameTakenSinceFetch_RetriesWithNextID(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	origType := config.NamingHashType
+	t.Cleanup(func() { config.NamingHashType = origType })
+	config.NamingHashType = config.HashSequential
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"1", "theirs.txt", "theirs", time.Now())
+	require.NoError(t, repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(config.BranchPrefix+"1")))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	// Someone else pushes 8stash/1 right after this clone fetched, so the first attempt
+	// knows nothing about it.
+	calls := 0
+	t.Cleanup(func() { stashNamesInUse = gitx.StashNamesInUse })
+	stashNamesInUse = func(prefix string) ([]string, error) {
+		calls++
+		if calls == 1 {
+			require.NoError(t, repo.Storer.RemoveReference(plumbing.NewRemoteReferenceName("origin", prefix+"1")))
+			return nil, nil
+		}
+		return gitx.StashNamesInUse(prefix)
+	}
+
+	// Act
+	name, err := HandlePush(PushOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, config.BranchPrefix+"2", name)
+	assert.Equal(t, 2, calls)
+	stashes, err := gitx.ListStashes(config.BranchPrefix)
+	require.NoError(t, err)
+	require.Len(t, stashes, 2)
+	assert.Equal(t, []string{"1", "2"}, []string{stashes[0].ID, stashes[1].ID})
+	_, err = os.Stat(filepath.Join(localPath, "wip.txt"))
+	assert.True(t, os.IsNotExist(err), "the changes are stashed under the next id")
+}
+
+func TestHandlePush_NamingTemplate_ListAndPopByID(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	origTemplate, origPrefix, origType := config.NamingTemplate, config.BranchPrefix, config.NamingHashType
+	t.Cleanup(func() {
+		config.NamingTemplate, config.BranchPrefix, config.NamingHashType = origTemplate, origPrefix, origType
+	})
+	config.NamingTemplate = "wip/{user}/{base}/{id}"
+	config.BranchPrefix = "wip/"
+	config.NamingHashType = config.HashSequential
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Jane Doe"
+	require.NoError(t, repo.SetConfig(cfg))
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	// Act
+	stashName, err := HandlePush(PushOptions{})
+	require.NoError(t, err)
+	stashes, listErr := Retrieve8stashList()
+	popErr := HandlePop(StashSelector{Query: "1"}, ApplyOptions{})
+
+	// Assert
+	assert.Equal(t, "wip/jane-doe/main/1", stashName)
+	require.NoError(t, listErr)
+	require.Len(t, stashes, 1)
+	assert.Equal(t, "1", stashes[0].ID)
+	require.NoError(t, popErr)
+	content, err := os.ReadFile(filepath.Join(localPath, "wip.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "wip", string(content))
+}
+
+// pushStashWithLocalCommit commits local.txt without pushing it, leaves wip.txt
+// uncommitted and pushes both with --with-commits. It returns the stash, the local
+// commit and the upstream commit it is based on.
+func pushStashWithLocalCommit(t *testing.T, repo *git.Repository, wt *git.Worktree, localPath string) (gitx.Stash, plumbing.Hash, plumbing.Hash) {
+	t.Helper()
+	upstream, err := repo.Head()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "local.txt"), []byte("committed"), 0o644))
+	_, err = wt.Add("local.txt")
+	require.NoError(t, err)
+	localCommit, err := wt.Commit("local work", &git.CommitOptions{
+		Author: &object.Signature{Name: "T", Email: "t@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(localPath, "wip.txt"), []byte("wip"), 0o644))
+
+	stashName, err := HandlePush(PushOptions{WithCommits: true})
+	require.NoError(t, err)
+	stashes, err := gitx.ListStashes(config.BranchPrefix)
+	require.NoError(t, err)
+	require.Len(t, stashes, 1)
+	require.Equal(t, stashName, stashes[0].Branch)
+	return stashes[0], localCommit, upstream.Hash()
+}
+
+func TestHandlePush_WithCommits_IncludesUnpushedCommits(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	// Act
+	stash, localCommit, upstream := pushStashWithLocalCommit(t, repo, wt, localPath)
+
+	// Assert
+	assert.True(t, stash.WithCommits)
+	assert.Equal(t, upstream, stash.BaseCommit)
+	commit, err := repo.CommitObject(stash.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{localCommit}, commit.ParentHashes, "the WIP commit sits on the local commit")
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, localCommit, head.Hash(), "the local branch keeps its commits")
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean())
+}
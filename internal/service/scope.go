@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"8stash/internal/config"
+	"8stash/internal/gitx"
+)
+
+// Scope narrows the stashes list, pop and cleanup look at to those of one person.
+type Scope struct {
+	// Mine keeps the stashes authored by the current git user.
+	Mine bool
+	// Author keeps the stashes whose author name or email contains the given text.
+	Author string
+	// All ignores the default scope from the config.
+	All bool
+}
+
+// mine reports whether only the current user's stashes are in scope.
+func (s Scope) mine() bool {
+	if s.Mine {
+		return true
+	}
+	return config.DefaultScope == config.ScopeMine && !s.All && s.Author == ""
+}
+
+// applyScope returns the stashes in scope, matching the author identity that push
+// records on every stash commit.
+func applyScope(stashes []gitx.Stash, scope Scope) ([]gitx.Stash, error) {
+	if scope.Author != "" {
+		stashes = filterStashes(stashes, func(s gitx.Stash) bool {
+			return authorMatches(s, scope.Author)
+		})
+	}
+	if !scope.mine() {
+		return stashes, nil
+	}
+	name, email, err := gitx.CurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" && email == "" {
+		return nil, fmt.Errorf("showing only your stashes needs user.name or user.email in your git config; use --all to see everyone's")
+	}
+	return filterStashes(stashes, func(s gitx.Stash) bool {
+		return isSameUser(s, name, email)
+	}), nil
+}
+
+// scopeSelection applies scope to the candidates of a selector. A stash named by its
+// id or branch is always found, so a stash handed over by a colleague can be popped.
+func scopeSelection(stashes []gitx.Stash, sel StashSelector, scope Scope) ([]gitx.Stash, error) {
+	if sel.Query != "" && isPlainID(sel) {
+		return stashes, nil
+	}
+	if sel.Author != "" {
+		// The selector already says whose stash it is.
+		scope.All = true
+	}
+	return applyScope(stashes, scope)
+}
+
+func authorMatches(s gitx.Stash, pattern string) bool {
+	pattern = strings.ToLower(pattern)
+	return strings.Contains(strings.ToLower(s.AuthorName), pattern) ||
+		strings.Contains(strings.ToLower(s.AuthorEmail), pattern)
+}
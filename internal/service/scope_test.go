@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/config"
+	"8stash/internal/gitx"
+	"8stash/internal/test"
+)
+
+// setupAsAlice sets up a test repository whose git user is Alice.
+func setupAsAlice(t *testing.T) (string, func()) {
+	t.Helper()
+	localPath, cleanup := test.SetupTestRepo(t)
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Alice"
+	cfg.User.Email = "alice@example.com"
+	require.NoError(t, repo.SetConfig(cfg))
+	origScope := config.DefaultScope
+	t.Cleanup(func() { config.DefaultScope = origScope })
+	return localPath, cleanup
+}
+
+func stashIDs(stashes []gitx.Stash) []string {
+	var ids []string
+	for _, s := range stashes {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestApplyScope(t *testing.T) {
+	testCases := []struct {
+		name         string
+		defaultScope config.ScopeMode
+		scope        Scope
+		want         []string
+	}{
+		{"everyone by default", config.ScopeAll, Scope{}, []string{"1234", "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", "5678", "brave-otter"}},
+		{"mine", config.ScopeAll, Scope{Mine: true}, []string{"1234", "5678"}},
+		{"author pattern", config.ScopeAll, Scope{Author: "BOB@"}, []string{"3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", "brave-otter"}},
+		{"mine from config", config.ScopeMine, Scope{}, []string{"1234", "5678"}},
+		{"all overrides config", config.ScopeMine, Scope{All: true}, []string{"1234", "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", "5678", "brave-otter"}},
+		{"author overrides config", config.ScopeMine, Scope{Author: "bob"}, []string{"3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", "brave-otter"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			_, cleanup := setupAsAlice(t)
+			defer cleanup()
+			config.DefaultScope = tc.defaultScope
+
+			// Act
+			stashes, err := applyScope(selectorFixture(), tc.scope)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, stashIDs(stashes))
+		})
+	}
+}
+
+func TestApplyScope_MineWithoutGitUser_Error(t *testing.T) {
+	// Arrange
+	_, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Act
+	_, err := applyScope(selectorFixture(), Scope{Mine: true})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "needs user.name or user.email")
+}
+
+func TestScopeSelection_ExplicitIDIgnoresDefaultScope(t *testing.T) {
+	// Arrange
+	_, cleanup := setupAsAlice(t)
+	defer cleanup()
+	config.DefaultScope = config.ScopeMine
+
+	// Act
+	byID, errByID := scopeSelection(selectorFixture(), StashSelector{Query: "brave-otter"}, Scope{})
+	latest, errLatest := scopeSelection(selectorFixture(), StashSelector{Query: SelectLatest}, Scope{})
+
+	// Assert
+	require.NoError(t, errByID)
+	assert.Len(t, byID, 4)
+	require.NoError(t, errLatest)
+	assert.Equal(t, []string{"1234", "5678"}, stashIDs(latest))
+}
+
+func TestHandlePop_DefaultScopeMine_PopsOwnStashAmongOthers(t *testing.T) {
+	// Arrange
+	localPath, cleanup := setupAsAlice(t)
+	defer cleanup()
+	config.DefaultScope = config.ScopeMine
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	bob := &object.Signature{Name: "Bob", Email: "bob@example.com", When: time.Now()}
+	alice := &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()}
+	test.CreateAndPushStashBranchWithAuthor(t, repo, wt, localPath, config.BranchPrefix+"1", "bob.txt", "bob", bob)
+	test.CreateAndPushStashBranchWithAuthor(t, repo, wt, localPath, config.BranchPrefix+"2", "alice.txt", "alice", alice)
+
+	// Act
+	err = HandlePop(StashSelector{}, ApplyOptions{})
+
+	// Assert
+	require.NoError(t, err)
+	assert.FileExists(t, "alice.txt")
+	assert.NoFileExists(t, "bob.txt")
+	stashes, err := Retrieve8stashList()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, stashIDs(stashes))
+}
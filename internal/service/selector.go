@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"8stash/internal/gitx"
+)
+
+const (
+	SelectLatest = "@latest"
+	SelectMine   = "@mine"
+)
+
+// StashSelector chooses stashes by id, recency, author or message.
+// Query is a stash id, a full branch name, @latest or @mine.
+type StashSelector struct {
+	Query  string
+	Author string
+	Grep   string
+}
+
+func (s StashSelector) IsEmpty() bool {
+	return s.Query == "" && s.Author == "" && s.Grep == ""
+}
+
+func (s StashSelector) String() string {
+	var parts []string
+	if s.Query != "" {
+		parts = append(parts, s.Query)
+	}
+	if s.Author != "" {
+		parts = append(parts, "--author "+s.Author)
+	}
+	if s.Grep != "" {
+		parts = append(parts, "--grep "+s.Grep)
+	}
+	return strings.Join(parts, " ")
+}
+
+// resolveStash returns the single stash matching the selector.
+func resolveStash(stashes []gitx.Stash, sel StashSelector) (gitx.Stash, error) {
+	matches, err := selectStashes(stashes, sel)
+	if err != nil {
+		return gitx.Stash{}, err
+	}
+	switch len(matches) {
+	case 0:
+		return gitx.Stash{}, fmt.Errorf("no stash matches %s", sel)
+	case 1:
+		return matches[0], nil
+	default:
+		return gitx.Stash{}, ambiguousSelectorError(sel, matches)
+	}
+}
+
+func selectStashes(stashes []gitx.Stash, sel StashSelector) ([]gitx.Stash, error) {
+	matches := stashes
+	if sel.Author != "" {
+		matches = filterStashes(matches, func(s gitx.Stash) bool {
+			return authorMatches(s, sel.Author)
+		})
+	}
+	if sel.Grep != "" {
+		re, err := regexp.Compile(sel.Grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+		matches = filterStashes(matches, func(s gitx.Stash) bool {
+			return re.MatchString(s.Message)
+		})
+	}
+
+	switch sel.Query {
+	case "":
+		return matches, nil
+	case SelectLatest:
+		return newestStash(matches), nil
+	case SelectMine:
+		name, email, err := gitx.CurrentUser()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" && email == "" {
+			return nil, fmt.Errorf("%s needs user.name or user.email in your git config", SelectMine)
+		}
+		return newestStash(filterStashes(matches, func(s gitx.Stash) bool {
+			return isSameUser(s, name, email)
+		})), nil
+	default:
+		return filterStashes(matches, func(s gitx.Stash) bool {
+			// ids are generated in lower case, so one typed as heard from a colleague still matches
+			return strings.EqualFold(s.ID, sel.Query) || s.Branch == sel.Query
+		}), nil
+	}
+}
+
+func filterStashes(stashes []gitx.Stash, keep func(gitx.Stash) bool) []gitx.Stash {
+	var filtered []gitx.Stash
+	for _, s := range stashes {
+		if keep(s) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func newestStash(stashes []gitx.Stash) []gitx.Stash {
+	if len(stashes) == 0 {
+		return nil
+	}
+	newest := stashes[0]
+	for _, s := range stashes[1:] {
+		if s.Time.After(newest.Time) {
+			newest = s
+		}
+	}
+	return []gitx.Stash{newest}
+}
+
+// isSameUser compares by email when both sides have one and falls back to the name otherwise.
+func isSameUser(s gitx.Stash, name, email string) bool {
+	if email != "" && s.AuthorEmail != "" {
+		return strings.EqualFold(s.AuthorEmail, email)
+	}
+	return name != "" && s.AuthorName == name
+}
+
+func ambiguousSelectorError(sel StashSelector, candidates []gitx.Stash) error {
+	var b strings.Builder
+	if sel.IsEmpty() {
+		fmt.Fprintf(&b, "%d stashes found; select one of:", len(candidates))
+	} else {
+		fmt.Fprintf(&b, "%s matches %d stashes; select one of:", sel, len(candidates))
+	}
+	for _, s := range candidates {
+		fmt.Fprintf(&b, "\n  %-20s %-15s %s", s.ID, s.AuthorName, s.Message)
+	}
+	return errors.New(b.String())
+}
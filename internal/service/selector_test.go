@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/gitx"
+	"8stash/internal/test"
+)
+
+func selectorFixture() []gitx.Stash {
+	now := time.Now()
+	return []gitx.Stash{
+		{ID: "1234", Branch: "8stash/1234", AuthorName: "Alice", AuthorEmail: "alice@example.com", Message: "fix login form", Time: now.Add(-3 * time.Hour)},
+		{ID: "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", Branch: "8stash/3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90", AuthorName: "Bob", AuthorEmail: "bob@example.com", Message: "wip: login api", Time: now.Add(-1 * time.Hour)},
+		{ID: "5678", Branch: "8stash/5678", AuthorName: "Alice", AuthorEmail: "alice@example.com", Message: "refactor footer", Time: now.Add(-2 * time.Hour)},
+		{ID: "brave-otter", Branch: "8stash/brave-otter", AuthorName: "Bob", AuthorEmail: "bob@example.com", Message: "header spacing", Time: now.Add(-4 * time.Hour)},
+	}
+}
+
+func TestResolveStash_SelectsByQueryAuthorAndGrep(t *testing.T) {
+	testCases := []struct {
+		name string
+		sel  StashSelector
+		want string
+	}{
+		{"numeric id", StashSelector{Query: "1234"}, "1234"},
+		{"uuid id", StashSelector{Query: "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90"}, "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90"},
+		{"full branch name", StashSelector{Query: "8stash/5678"}, "5678"},
+		{"words id", StashSelector{Query: "brave-otter"}, "brave-otter"},
+		{"words id read out with capitals", StashSelector{Query: "Brave-Otter"}, "brave-otter"},
+		{"words branch name", StashSelector{Query: "8stash/brave-otter"}, "brave-otter"},
+		{"latest", StashSelector{Query: SelectLatest}, "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90"},
+		{"latest by author", StashSelector{Query: SelectLatest, Author: "alice"}, "5678"},
+		{"grep", StashSelector{Grep: "^fix"}, "1234"},
+		{"author and grep", StashSelector{Author: "ALICE@example", Grep: "footer"}, "5678"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			stash, err := resolveStash(selectorFixture(), tc.sel)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, stash.ID)
+		})
+	}
+}
+
+func TestResolveStash_Ambiguous_ListsCandidates(t *testing.T) {
+	// Act
+	_, err := resolveStash(selectorFixture(), StashSelector{Grep: "login"})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "--grep login matches 2 stashes")
+	assert.ErrorContains(t, err, "1234")
+	assert.ErrorContains(t, err, "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90")
+	assert.NotContains(t, err.Error(), "5678")
+}
+
+func TestResolveStash_NoMatch_Error(t *testing.T) {
+	// Act
+	_, err := resolveStash(selectorFixture(), StashSelector{Author: "carol"})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no stash matches --author carol")
+}
+
+func TestResolveStash_InvalidGrep_Error(t *testing.T) {
+	// Act
+	_, err := resolveStash(selectorFixture(), StashSelector{Grep: "("})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid --grep pattern")
+}
+
+func TestResolveStash_Mine_SelectsNewestStashOfGitUser(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	cfg, err := repo.Config()
+	require.NoError(t, err)
+	cfg.User.Name = "Alice"
+	cfg.User.Email = "Alice@Example.com"
+	require.NoError(t, repo.SetConfig(cfg))
+
+	// Act
+	stash, err := resolveStash(selectorFixture(), StashSelector{Query: SelectMine})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "5678", stash.ID)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+
+	"8stash/internal/gitx"
+)
+
+type ShowOptions struct {
+	Stat     bool
+	NameOnly bool
+}
+
+func HandleShow(sel StashSelector, opts ShowOptions) error {
+	if opts.Stat && opts.NameOnly {
+		return errors.New("--stat and --name-only cannot be combined")
+	}
+	stashes, err := Retrieve8stashList()
+	if err != nil {
+		return err
+	}
+	stash, err := resolveStash(stashes, sel)
+	if err != nil {
+		return err
+	}
+	patch, err := gitx.StashPatch(stash.Hash)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.Stat:
+		printStat(os.Stdout, patch)
+	case opts.NameOnly:
+		printNames(os.Stdout, patch)
+	default:
+		fmt.Fprintf(os.Stdout, "stash %s\nAuthor: %s <%s>\nDate:   %s\n\n    %s\n\n", stash.ID, stash.AuthorName, stash.AuthorEmail, stash.Time.Format(time.RFC1123Z), stash.Message)
+		return patch.Encode(os.Stdout)
+	}
+	return nil
+}
+
+func printStat(w io.Writer, patch *object.Patch) {
+	stats := patch.Stats()
+	var added, deleted int
+	for _, s := range stats {
+		added += s.Addition
+		deleted += s.Deletion
+	}
+	fmt.Fprint(w, stats.String())
+	fmt.Fprintf(w, " %d files changed, %d insertions(+), %d deletions(-)\n", len(stats), added, deleted)
+}
+
+func printNames(w io.Writer, patch *object.Patch) {
+	for _, fp := range patch.FilePatches() {
+		from, to := fp.Files()
+		if to != nil {
+			fmt.Fprintln(w, to.Path())
+		} else if from != nil {
+			fmt.Fprintln(w, from.Path())
+		}
+	}
+}
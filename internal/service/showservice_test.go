@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"8stash/internal/config"
+	"8stash/internal/test"
+)
+
+func setupShowRepo(t *testing.T) func() {
+	t.Helper()
+	localPath, cleanup := test.SetupTestRepo(t)
+
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	test.CreateAndPushStashBranch(t, repo, wt, localPath, config.BranchPrefix+"42", "show.txt", "line one\nline two\n", time.Now())
+	test.FetchAll(t, repo)
+	return cleanup
+}
+
+func TestHandleShow_PrintsPatchWithoutRemovingStash(t *testing.T) {
+	// Arrange
+	cleanup := setupShowRepo(t)
+	defer cleanup()
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleShow(StashSelector{Query: "42"}, ShowOptions{})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Contains(t, out, "stash 42")
+	assert.Contains(t, out, "diff --git a/show.txt b/show.txt")
+	assert.Contains(t, out, "+line one")
+	assert.Contains(t, out, "+line two")
+
+	stashes, err := Retrieve8stashList()
+	require.NoError(t, err)
+	require.Len(t, stashes, 1, "show must not remove the stash")
+	assert.Equal(t, "42", stashes[0].ID)
+}
+
+func TestHandleShow_Stat_PrintsSummary(t *testing.T) {
+	// Arrange
+	cleanup := setupShowRepo(t)
+	defer cleanup()
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleShow(StashSelector{Query: "42"}, ShowOptions{Stat: true})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Contains(t, out, "show.txt | 2 ++")
+	assert.Contains(t, out, "1 files changed, 2 insertions(+), 0 deletions(-)")
+	assert.NotContains(t, out, "+line one")
+}
+
+func TestHandleShow_NameOnly_PrintsFileNames(t *testing.T) {
+	// Arrange
+	cleanup := setupShowRepo(t)
+	defer cleanup()
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleShow(StashSelector{Query: "42"}, ShowOptions{NameOnly: true})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Equal(t, "show.txt\n", out)
+}
+
+func TestHandleShow_UnknownStash_Error(t *testing.T) {
+	// Arrange
+	cleanup := setupShowRepo(t)
+	defer cleanup()
+
+	// Act
+	err := HandleShow(StashSelector{Query: "99"}, ShowOptions{})
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "no stash matches 99")
+}
+
+func TestHandleShow_WithCommits_IncludesCarriedCommits(t *testing.T) {
+	// Arrange
+	localPath, cleanup := test.SetupTestRepo(t)
+	defer cleanup()
+	repo, err := git.PlainOpen(localPath)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	stash, _, _ := pushStashWithLocalCommit(t, repo, wt, localPath)
+
+	// Act
+	var actErr error
+	out := captureOutput(t, func() {
+		actErr = HandleShow(StashSelector{Query: stash.ID}, ShowOptions{})
+	})
+
+	// Assert
+	require.NoError(t, actErr)
+	assert.Contains(t, out, "diff --git a/wip.txt b/wip.txt")
+	assert.Contains(t, out, "diff --git a/local.txt b/local.txt", "the carried commit is part of the stash")
+	assert.Contains(t, out, "+committed")
+}
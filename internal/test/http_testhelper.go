@@ -0,0 +1,51 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v6/osfs"
+	githttp "github.com/go-git/go-git/v6/backend/http"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	httptransport "github.com/go-git/go-git/v6/plumbing/transport/http"
+	"github.com/stretchr/testify/require"
+)
+
+// ServeRepoOverHTTP serves the bare repository at repoPath over smart HTTP and
+// only accepts requests with the given basic auth credentials. It returns the clone url.
+func ServeRepoOverHTTP(t *testing.T, repoPath, username, password string) string {
+	t.Helper()
+	srv := httptest.NewServer(repoHandler(repoPath, username, password))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/" + filepath.Base(filepath.Clean(repoPath))
+}
+
+// ServeRepoOverHTTPS is ServeRepoOverHTTP over TLS. go-git trusts the test certificate
+// until the test ends.
+func ServeRepoOverHTTPS(t *testing.T, repoPath, username, password string) string {
+	t.Helper()
+	srv := httptest.NewTLSServer(repoHandler(repoPath, username, password))
+	t.Cleanup(srv.Close)
+
+	orig, err := transport.Get("https")
+	require.NoError(t, err)
+	transport.Register("https", httptransport.NewTransport(&httptransport.TransportOptions{Client: srv.Client()}))
+	t.Cleanup(func() { transport.Register("https", orig) })
+	return srv.URL + "/" + filepath.Base(filepath.Clean(repoPath))
+}
+
+func repoHandler(repoPath, username, password string) http.Handler {
+	dir := filepath.Dir(filepath.Clean(repoPath))
+	backend := githttp.NewBackend(transport.NewFilesystemLoader(osfs.New(dir), false))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="8stash"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	})
+}
@@ -50,12 +50,11 @@ func TestArgValidation_NoArgs_DefaultsToPush(t *testing.T) {
 	args := []string{}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "push", op)
-	assert.Equal(t, 0, num)
 }
 
 func TestArgValidation_InvalidOperation_Error(t *testing.T) {
@@ -63,12 +62,11 @@ func TestArgValidation_InvalidOperation_Error(t *testing.T) {
 	args := []string{"unknown"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.Error(t, err)
 	assert.Equal(t, "", op)
-	assert.Equal(t, 0, num)
 	assert.ErrorContains(t, err, "invalid operation")
 }
 
@@ -77,12 +75,11 @@ func TestArgValidation_Drop_WithoutNumber_Error(t *testing.T) {
 	args := []string{"drop"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.Error(t, err)
 	assert.Equal(t, "", op)
-	assert.Equal(t, 0, num)
 	assert.ErrorContains(t, err, "operation requires a stash number")
 }
 
@@ -91,12 +88,11 @@ func TestArgValidation_Drop_WithNumber_Succeeds(t *testing.T) {
 	args := []string{"drop", "5"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "drop", op)
-	assert.Equal(t, 5, num)
 }
 
 func TestArgValidation_Pop_WithNumber_Succeeds(t *testing.T) {
@@ -104,12 +100,11 @@ func TestArgValidation_Pop_WithNumber_Succeeds(t *testing.T) {
 	args := []string{"pop", "3"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "pop", op)
-	assert.Equal(t, 3, num)
 }
 
 func TestArgValidation_Pop_WithoutNumber_Succeeds(t *testing.T) {
@@ -117,27 +112,26 @@ func TestArgValidation_Pop_WithoutNumber_Succeeds(t *testing.T) {
 	args := []string{"pop"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "pop", op)
-	assert.Equal(t, 0, num)
 }
 
-func TestArgValidation_InvalidNumber_Error(t *testing.T) {
-	// Arrange
-	args := []string{"pop", "abc"}
-
-	// Act
-	op, num, err := ArgValidation(args)
-
-	// Assert
-	require.Error(t, err)
-	assert.Equal(t, "", op)
-	assert.Equal(t, 0, num)
-	// strconv.Atoi error text includes "invalid syntax"
-	assert.ErrorContains(t, err, "invalid syntax")
+func TestArgValidation_Pop_WithUUIDOrSelector_Succeeds(t *testing.T) {
+	for _, args := range [][]string{
+		{"pop", "3f2c9a6e-8d41-4b7a-9c1e-2a5b6d7e8f90"},
+		{"pop", "@latest"},
+		{"drop", "--author", "alice"},
+	} {
+		// Act
+		op, err := ArgValidation(args)
+
+		// Assert
+		require.NoError(t, err, "args %v", args)
+		assert.Equal(t, args[0], op)
+	}
 }
 
 func TestArgValidation_CaseInsensitiveOperation(t *testing.T) {
@@ -145,12 +139,11 @@ func TestArgValidation_CaseInsensitiveOperation(t *testing.T) {
 	args := []string{"HeLp"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "help", op)
-	assert.Equal(t, 0, num)
 }
 
 func TestArgValidation_Cleanup_NoNumber_Succeeds(t *testing.T) {
@@ -158,12 +151,11 @@ func TestArgValidation_Cleanup_NoNumber_Succeeds(t *testing.T) {
 	args := []string{"cleanup"}
 
 	// Act
-	op, num, err := ArgValidation(args)
+	op, err := ArgValidation(args)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, "cleanup", op)
-	assert.Equal(t, 0, num)
 }
 
 func TestArgValidation_CleanupFastReturn(t *testing.T) {
@@ -172,28 +164,24 @@ func TestArgValidation_CleanupFastReturn(t *testing.T) {
         name          string
         args          []string
         expectedOp    string
-        expectedNum   int
         shouldError   bool
     }{
         {
             name:          "cleanup with no extra args",
             args:          []string{"cleanup"},
             expectedOp:    "cleanup",
-            expectedNum:   0,
             shouldError:   false,
         },
         {
             name:          "cleanup with flag-like extra args",
             args:          []string{"cleanup", "-d", "15"},
             expectedOp:    "cleanup",
-            expectedNum:   0,
             shouldError:   false,
         },
         {
             name:          "cleanup with different casing",
             args:          []string{"CLEANUP"},
             expectedOp:    "cleanup",
-            expectedNum:   0,
             shouldError:   false,
         },
     }
@@ -201,7 +189,7 @@ func TestArgValidation_CleanupFastReturn(t *testing.T) {
     for _, tc := range testCases {
         t.Run(tc.name, func(t *testing.T) {
             // Act
-            op, num, err := ArgValidation(tc.args)
+            op, err := ArgValidation(tc.args)
 
             // Assert
             if tc.shouldError {
@@ -209,8 +197,44 @@ func TestArgValidation_CleanupFastReturn(t *testing.T) {
             } else {
                 require.NoError(t, err)
                 assert.Equal(t, tc.expectedOp, op)
-                assert.Equal(t, tc.expectedNum, num)
             }
         })
     }
 }
+
+func TestArgValidation_List_WithFormatFlag_Succeeds(t *testing.T) {
+	// Arrange
+	args := []string{"list", "--format", "json"}
+
+	// Act
+	op, err := ArgValidation(args)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "list", op)
+}
+
+func TestArgValidation_Show_WithoutID_Error(t *testing.T) {
+	// Arrange
+	args := []string{"show"}
+
+	// Act
+	op, err := ArgValidation(args)
+
+	// Assert
+	require.Error(t, err)
+	assert.Equal(t, "", op)
+	assert.ErrorContains(t, err, "operation requires a stash number")
+}
+
+func TestArgValidation_Show_WithFlags_Succeeds(t *testing.T) {
+	// Arrange
+	args := []string{"show", "--stat", "42"}
+
+	// Act
+	op, err := ArgValidation(args)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "show", op)
+}